@@ -21,14 +21,19 @@ limitations under the License.
 package app
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/kubernetes/pkg/controller/daemon"
 	"k8s.io/kubernetes/pkg/controller/deployment"
+	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
 	"k8s.io/kubernetes/pkg/controller/replicaset"
 	"k8s.io/kubernetes/pkg/controller/statefulset"
 )
@@ -83,15 +88,78 @@ func startDeploymentController(ctx ControllerContext) (http.Handler, bool, error
 	if !ctx.AvailableResources[schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}] {
 		return nil, false, nil
 	}
+	client := ctx.ClientBuilder.ClientOrDie("deployment-controller")
+	dInformer := ctx.InformerFactory.Apps().V1().Deployments()
+	rsInformer := ctx.InformerFactory.Apps().V1().ReplicaSets()
+	podInformer := ctx.InformerFactory.Core().V1().Pods()
+	// With a watch label selector configured, the workload informers come from a
+	// dedicated filtered factory so the caches only hold matching objects.
+	var filteredFactory informers.SharedInformerFactory
+	if selector := ctx.ComponentConfig.DeploymentController.WatchLabelSelector; selector != "" {
+		filteredFactory = informers.NewSharedInformerFactoryWithOptions(client, 12*time.Hour,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = selector
+			}))
+		dInformer = filteredFactory.Apps().V1().Deployments()
+		rsInformer = filteredFactory.Apps().V1().ReplicaSets()
+		podInformer = filteredFactory.Core().V1().Pods()
+	}
 	dc, err := deployment.NewDeploymentController(
-		ctx.InformerFactory.Apps().V1().Deployments(),
-		ctx.InformerFactory.Apps().V1().ReplicaSets(),
-		ctx.InformerFactory.Core().V1().Pods(),
-		ctx.ClientBuilder.ClientOrDie("deployment-controller"),
+		dInformer,
+		rsInformer,
+		podInformer,
+		ctx.InformerFactory.Policy().V1().PodDisruptionBudgets(),
+		ctx.InformerFactory.Core().V1().Nodes(),
+		client,
 	)
 	if err != nil {
 		return nil, true, fmt.Errorf("error creating Deployment controller: %v", err)
 	}
-	go dc.Run(int(ctx.ComponentConfig.DeploymentController.ConcurrentDeploymentSyncs), ctx.Stop)
-	return nil, true, nil
+	if filteredFactory != nil {
+		filteredFactory.Start(ctx.Stop)
+	}
+	if path := ctx.ComponentConfig.DeploymentController.MutationAuditLogPath; path != "" {
+		auditFile, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, true, fmt.Errorf("error opening deployment controller mutation audit log: %v", err)
+		}
+		dc.EnableMutationAudit(auditFile)
+	}
+	if threshold := ctx.ComponentConfig.DeploymentController.NodeUnavailabilityThresholdPercent; threshold > 0 {
+		dc.SetNodeHoldThreshold(threshold)
+	}
+	if url := ctx.ComponentConfig.DeploymentController.RollbackWebhookURL; url != "" {
+		dc.SetRollbackWebhook(url)
+	}
+	if include, exclude := ctx.ComponentConfig.DeploymentController.AnnotationPropagationInclude, ctx.ComponentConfig.DeploymentController.AnnotationPropagationExclude; len(include) > 0 || len(exclude) > 0 {
+		deploymentutil.SetAnnotationPropagationPolicy(include, exclude)
+	}
+	if ctx.ComponentConfig.DeploymentController.DisableOrphanAdoption {
+		dc.SetDisableOrphanAdoption(true)
+	}
+	if min, max := ctx.ComponentConfig.DeploymentController.ProgressRecheckMinInterval.Duration, ctx.ComponentConfig.DeploymentController.ProgressRecheckMaxInterval.Duration; min > 0 || max > 0 {
+		dc.SetProgressRecheckIntervals(min, max)
+	}
+	if ctx.ComponentConfig.DeploymentController.ServerSideApplyStatus {
+		dc.SetServerSideApplyForStatus(true)
+	}
+	if interval := ctx.ComponentConfig.DeploymentController.StatusWriteMinInterval.Duration; interval > 0 {
+		dc.SetStatusWriteMinInterval(interval)
+	}
+	if count := ctx.ComponentConfig.DeploymentController.ShardCount; count > 1 {
+		dc.SetShard(ctx.ComponentConfig.DeploymentController.ShardIndex, count)
+	}
+	if cfg := ctx.ComponentConfig.DeploymentController; cfg.QueueBaseDelay.Duration > 0 || cfg.QueueMaxRetries > 0 {
+		dc.SetQueueRateLimiter(cfg.QueueBaseDelay.Duration, cfg.QueueMaxDelay.Duration, cfg.QueueMaxRetries)
+	}
+	if max := ctx.ComponentConfig.DeploymentController.MaxConcurrentDeploymentSyncs; max > ctx.ComponentConfig.DeploymentController.ConcurrentDeploymentSyncs {
+		dc.SetMaxWorkers(int(max))
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-ctx.Stop
+		cancel()
+	}()
+	go dc.Run(runCtx, int(ctx.ComponentConfig.DeploymentController.ConcurrentDeploymentSyncs))
+	return dc.DebugHandler(), true, nil
 }
@@ -35,6 +35,23 @@ func (o *DeploymentControllerOptions) AddFlags(fs *pflag.FlagSet) {
 
 	fs.Int32Var(&o.ConcurrentDeploymentSyncs, "concurrent-deployment-syncs", o.ConcurrentDeploymentSyncs, "The number of deployment objects that are allowed to sync concurrently. Larger number = more responsive deployments, but more CPU (and network) load")
 	fs.DurationVar(&o.DeploymentControllerSyncPeriod.Duration, "deployment-controller-sync-period", o.DeploymentControllerSyncPeriod.Duration, "Period for syncing the deployments.")
+	fs.StringVar(&o.MutationAuditLogPath, "deployment-controller-mutation-audit-log", o.MutationAuditLogPath, "If set, the deployment controller appends a JSONL record of every mutating API call it performs to this file.")
+	fs.Int32Var(&o.NodeUnavailabilityThresholdPercent, "deployment-controller-node-unavailability-threshold", o.NodeUnavailabilityThresholdPercent, "If positive, the deployment controller holds rollout progression while more than this percentage of nodes is unschedulable or not ready.")
+	fs.StringVar(&o.RollbackWebhookURL, "deployment-controller-rollback-webhook", o.RollbackWebhookURL, "If set, the deployment controller POSTs a JSON notification to this URL for every automatic rollback it performs.")
+	fs.StringSliceVar(&o.AnnotationPropagationInclude, "deployment-controller-annotation-include", o.AnnotationPropagationInclude, "Glob patterns of deployment annotations to copy to replica sets. Empty means all non-skipped annotations.")
+	fs.StringSliceVar(&o.AnnotationPropagationExclude, "deployment-controller-annotation-exclude", o.AnnotationPropagationExclude, "Glob patterns of deployment annotations never copied to replica sets; exclusions win over inclusions.")
+	fs.BoolVar(&o.DisableOrphanAdoption, "deployment-controller-disable-orphan-adoption", o.DisableOrphanAdoption, "If true, the deployment controller never adopts orphaned replica sets; it only manages replica sets it created.")
+	fs.DurationVar(&o.ProgressRecheckMinInterval.Duration, "deployment-controller-progress-recheck-min-interval", o.ProgressRecheckMinInterval.Duration, "If positive, the soonest a potentially stuck deployment is requeued for a progress check.")
+	fs.DurationVar(&o.ProgressRecheckMaxInterval.Duration, "deployment-controller-progress-recheck-max-interval", o.ProgressRecheckMaxInterval.Duration, "If positive, the latest a potentially stuck deployment is requeued for a progress check.")
+	fs.BoolVar(&o.ServerSideApplyStatus, "deployment-controller-server-side-apply-status", o.ServerSideApplyStatus, "If true, the deployment controller writes deployment status with server-side apply under a dedicated field manager.")
+	fs.DurationVar(&o.StatusWriteMinInterval.Duration, "deployment-controller-status-write-min-interval", o.StatusWriteMinInterval.Duration, "If positive, bounds how often deployment status is written per deployment; faster writes are coalesced into a later sync.")
+	fs.Int32Var(&o.ShardIndex, "deployment-controller-shard-index", o.ShardIndex, "Index of this deployment controller instance's namespace shard. Only used when --deployment-controller-shard-count is greater than 1.")
+	fs.Int32Var(&o.ShardCount, "deployment-controller-shard-count", o.ShardCount, "Number of deployment controller instances sharding namespaces by consistent hash. At most 1 disables sharding.")
+	fs.StringVar(&o.WatchLabelSelector, "deployment-controller-watch-label-selector", o.WatchLabelSelector, "If set, restricts the deployment controller's deployment, replica set and pod informers to objects matching this label selector.")
+	fs.DurationVar(&o.QueueBaseDelay.Duration, "deployment-controller-queue-base-delay", o.QueueBaseDelay.Duration, "Base delay of the deployment queue's exponential per-item backoff. Only used together with --deployment-controller-queue-max-delay.")
+	fs.DurationVar(&o.QueueMaxDelay.Duration, "deployment-controller-queue-max-delay", o.QueueMaxDelay.Duration, "Maximum delay of the deployment queue's exponential per-item backoff. Only used together with --deployment-controller-queue-base-delay.")
+	fs.Int32Var(&o.QueueMaxRetries, "deployment-controller-queue-max-retries", o.QueueMaxRetries, "If positive, how often a failing deployment sync is retried before the deployment is dropped from the queue.")
+	fs.Int32Var(&o.MaxConcurrentDeploymentSyncs, "max-concurrent-deployment-syncs", o.MaxConcurrentDeploymentSyncs, "If larger than concurrent-deployment-syncs, the deployment controller grows its worker pool up to this size while its queue stays deep.")
 }
 
 // ApplyTo fills up DeploymentController config with options.
@@ -45,6 +62,23 @@ func (o *DeploymentControllerOptions) ApplyTo(cfg *deploymentconfig.DeploymentCo
 
 	cfg.ConcurrentDeploymentSyncs = o.ConcurrentDeploymentSyncs
 	cfg.DeploymentControllerSyncPeriod = o.DeploymentControllerSyncPeriod
+	cfg.MutationAuditLogPath = o.MutationAuditLogPath
+	cfg.NodeUnavailabilityThresholdPercent = o.NodeUnavailabilityThresholdPercent
+	cfg.RollbackWebhookURL = o.RollbackWebhookURL
+	cfg.AnnotationPropagationInclude = o.AnnotationPropagationInclude
+	cfg.AnnotationPropagationExclude = o.AnnotationPropagationExclude
+	cfg.DisableOrphanAdoption = o.DisableOrphanAdoption
+	cfg.ProgressRecheckMinInterval = o.ProgressRecheckMinInterval
+	cfg.ProgressRecheckMaxInterval = o.ProgressRecheckMaxInterval
+	cfg.ServerSideApplyStatus = o.ServerSideApplyStatus
+	cfg.StatusWriteMinInterval = o.StatusWriteMinInterval
+	cfg.ShardIndex = o.ShardIndex
+	cfg.ShardCount = o.ShardCount
+	cfg.WatchLabelSelector = o.WatchLabelSelector
+	cfg.QueueBaseDelay = o.QueueBaseDelay
+	cfg.QueueMaxDelay = o.QueueMaxDelay
+	cfg.QueueMaxRetries = o.QueueMaxRetries
+	cfg.MaxConcurrentDeploymentSyncs = o.MaxConcurrentDeploymentSyncs
 
 	return nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
@@ -307,9 +309,6 @@ func NewReplicaSetControllerRefManager(
 // reconciliation was necessary. The list of ReplicaSets that you now own is
 // returned.
 func (m *ReplicaSetControllerRefManager) ClaimReplicaSets(sets []*apps.ReplicaSet) ([]*apps.ReplicaSet, error) {
-	var claimed []*apps.ReplicaSet
-	var errlist []error
-
 	match := func(obj metav1.Object) bool {
 		return m.Selector.Matches(labels.Set(obj.GetLabels()))
 	}
@@ -320,19 +319,40 @@ func (m *ReplicaSetControllerRefManager) ClaimReplicaSets(sets []*apps.ReplicaSe
 		return m.ReleaseReplicaSet(obj.(*apps.ReplicaSet))
 	}
 
-	for _, rs := range sets {
+	// Claims are independent patches; run them with bounded concurrency so adopting or
+	// releasing many replica sets at once (selector changes, controller restarts)
+	// doesn't serialize one API round trip after another.
+	claimedResults := make([]*apps.ReplicaSet, len(sets))
+	claimErrs := make([]error, len(sets))
+	workqueue.ParallelizeUntil(context.TODO(), maxConcurrentClaims, len(sets), func(i int) {
+		rs := sets[i]
 		ok, err := m.ClaimObject(rs, match, adopt, release)
 		if err != nil {
-			errlist = append(errlist, err)
-			continue
+			claimErrs[i] = err
+			return
 		}
 		if ok {
-			claimed = append(claimed, rs)
+			claimedResults[i] = rs
+		}
+	})
+
+	var claimed []*apps.ReplicaSet
+	var errlist []error
+	for i := range sets {
+		if claimErrs[i] != nil {
+			errlist = append(errlist, claimErrs[i])
+			continue
+		}
+		if claimedResults[i] != nil {
+			claimed = append(claimed, claimedResults[i])
 		}
 	}
 	return claimed, utilerrors.NewAggregate(errlist)
 }
 
+// maxConcurrentClaims bounds how many adoption/orphaning patches are in flight at once.
+const maxConcurrentClaims = 5
+
 // AdoptReplicaSet sends a patch to take control of the ReplicaSet. It returns
 // the error if the patching fails.
 func (m *ReplicaSetControllerRefManager) AdoptReplicaSet(rs *apps.ReplicaSet) error {
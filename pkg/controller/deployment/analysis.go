@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
+	"k8s.io/kubernetes/pkg/features"
+)
+
+// AnalysisVerdict is the outcome of an analysis run for a rollout step.
+type AnalysisVerdict string
+
+const (
+	// AnalysisPass lets the rollout continue to the next step.
+	AnalysisPass AnalysisVerdict = "Pass"
+	// AnalysisFail blocks the rollout; depending on the provider's advice the
+	// controller may also roll back automatically.
+	AnalysisFail AnalysisVerdict = "Fail"
+	// AnalysisInconclusive holds the rollout at its current step and retries later.
+	AnalysisInconclusive AnalysisVerdict = "Inconclusive"
+)
+
+// AnalysisProvider is consulted between rollout steps, typically backed by a metrics
+// system such as Prometheus. A failing analysis blocks promotion of the new revision and
+// can request an automatic rollback.
+type AnalysisProvider interface {
+	// AnalyzeRollout judges the health of the deployment's in-flight new revision. The
+	// message is surfaced in events; rollbackOnFailure requests an automatic rollback to
+	// the previous revision when the verdict is AnalysisFail.
+	AnalyzeRollout(deployment *apps.Deployment, newRS *apps.ReplicaSet) (verdict AnalysisVerdict, message string, rollbackOnFailure bool, err error)
+}
+
+// analysisRetryPeriod is how long an inconclusive analysis holds the rollout before the
+// deployment is rechecked.
+const analysisRetryPeriod = 30 * time.Second
+
+// SetAnalysisProvider installs an analysis provider consulted between rollout steps. It
+// must be called before Run and only takes effect with the DeploymentRolloutAnalysis
+// feature gate enabled.
+func (dc *DeploymentController) SetAnalysisProvider(provider AnalysisProvider) {
+	dc.analysisProvider = provider
+}
+
+// analysisAllowsPromotion consults the configured analysis provider before old replica
+// sets are scaled down. It returns false when the rollout must hold at its current step.
+func (dc *DeploymentController) analysisAllowsPromotion(ctx context.Context, deployment *apps.Deployment, newRS *apps.ReplicaSet) (bool, error) {
+	if dc.analysisProvider == nil || newRS == nil || !utilfeature.DefaultFeatureGate.Enabled(features.DeploymentRolloutAnalysis) {
+		return true, nil
+	}
+	verdict, message, rollbackOnFailure, err := dc.analysisProvider.AnalyzeRollout(deployment, newRS)
+	if err != nil {
+		return false, err
+	}
+	switch verdict {
+	case AnalysisFail:
+		dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, "RolloutAnalysisFailed",
+			"Analysis failed for replica set %s: %s", newRS.Name, message)
+		if rollbackOnFailure {
+			// Route the reaction through the regular rollback path.
+			setRollbackTo(deployment, &extensions.RollbackConfig{Revision: 0})
+			deployment.Annotations[deploymentutil.RollbackTriggerAnnotation] = "AnalysisFailed"
+			updated, err := dc.client.AppsV1().Deployments(deployment.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+			if err != nil {
+				return false, err
+			}
+			// The caller's sync continues with this object; keep it at the server's
+			// resourceVersion so the status write that follows doesn't conflict.
+			if updated != nil {
+				*deployment = *updated
+			}
+			dc.auditMutation("Deployment", deployment.Namespace, deployment.Name, "update", "requested rollback", "RolloutAnalysisFailed")
+		}
+		return false, nil
+	case AnalysisInconclusive:
+		condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentRolloutHeld, v1.ConditionTrue, deploymentutil.HeldByAnalysisReason,
+			fmt.Sprintf("Promotion is held pending a conclusive analysis: %s", message))
+		deploymentutil.SetDeploymentCondition(&deployment.Status, *condition)
+		dc.enqueueAfter(deployment, analysisRetryPeriod)
+		return false, nil
+	}
+	// A passing analysis lifts an analysis hold.
+	if held := deploymentutil.GetDeploymentCondition(deployment.Status, deploymentutil.DeploymentRolloutHeld); held != nil && held.Reason == deploymentutil.HeldByAnalysisReason {
+		deploymentutil.RemoveDeploymentCondition(&deployment.Status, deploymentutil.DeploymentRolloutHeld)
+	}
+	return true, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
+	"k8s.io/kubernetes/pkg/features"
+)
+
+type fakeAnalysisProvider struct {
+	verdict  AnalysisVerdict
+	rollback bool
+}
+
+func (f *fakeAnalysisProvider) AnalyzeRollout(d *apps.Deployment, newRS *apps.ReplicaSet) (AnalysisVerdict, string, bool, error) {
+	return f.verdict, "fake analysis", f.rollback, nil
+}
+
+func TestAnalysisAllowsPromotion(t *testing.T) {
+	defer featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.DeploymentRolloutAnalysis, true)()
+
+	tests := []struct {
+		name            string
+		provider        AnalysisProvider
+		expectedPromote bool
+	}{
+		{
+			name:            "no provider installed",
+			expectedPromote: true,
+		},
+		{
+			name:            "passing analysis",
+			provider:        &fakeAnalysisProvider{verdict: AnalysisPass},
+			expectedPromote: true,
+		},
+		{
+			name:     "failing analysis",
+			provider: &fakeAnalysisProvider{verdict: AnalysisFail},
+		},
+		{
+			name:     "inconclusive analysis",
+			provider: &fakeAnalysisProvider{verdict: AnalysisInconclusive},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newDeployment("foo", 3, nil, nil, nil, map[string]string{"foo": "bar"})
+			newRS := newReplicaSet(d, "foo-1", 3)
+			dc := &DeploymentController{
+				client:        &fake.Clientset{},
+				eventRecorder: &record.FakeRecorder{},
+				queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment"),
+			}
+			dc.enqueueDeployment = dc.enqueue
+			dc.SetAnalysisProvider(test.provider)
+
+			promote, err := dc.analysisAllowsPromotion(context.TODO(), d, newRS)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if promote != test.expectedPromote {
+				t.Errorf("expected promote=%t, got %t", test.expectedPromote, promote)
+			}
+		})
+	}
+}
+
+func TestFailedAnalysisRequestsRollback(t *testing.T) {
+	defer featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.DeploymentRolloutAnalysis, true)()
+
+	d := newDeployment("foo", 3, nil, nil, nil, map[string]string{"foo": "bar"})
+	newRS := newReplicaSet(d, "foo-1", 3)
+	dc := &DeploymentController{
+		client:        fake.NewSimpleClientset(d),
+		eventRecorder: &record.FakeRecorder{},
+	}
+	dc.SetAnalysisProvider(&fakeAnalysisProvider{verdict: AnalysisFail, rollback: true})
+
+	promote, err := dc.analysisAllowsPromotion(context.TODO(), d, newRS)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if promote {
+		t.Errorf("expected the failing analysis to block promotion")
+	}
+	if getRollbackTo(d) == nil {
+		t.Errorf("expected a rollback to be requested")
+	}
+}
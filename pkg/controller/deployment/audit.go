@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// mutationAuditEntry is a single line in the controller's mutation audit log. It describes
+// one mutating API call the deployment controller performed, independent of apiserver audit.
+type mutationAuditEntry struct {
+	Time      metav1.Time `json:"time"`
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Verb      string      `json:"verb"`
+	// Summary is a short human-readable description of the change, e.g. "replicas 3 -> 1".
+	Summary string `json:"summary,omitempty"`
+	// Reason explains why the controller performed the call, e.g. "RollingUpdateScaleDown".
+	Reason string `json:"reason,omitempty"`
+}
+
+// mutationAudit serializes mutation audit entries as JSON lines to a sink. Writes from
+// concurrent workers are serialized by a mutex; callers provide rotation by passing a
+// rotating writer as the sink.
+type mutationAudit struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newMutationAudit(sink io.Writer) *mutationAudit {
+	return &mutationAudit{enc: json.NewEncoder(sink)}
+}
+
+func (a *mutationAudit) record(entry mutationAuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.enc.Encode(&entry); err != nil {
+		utilruntime.HandleError(err)
+	}
+}
+
+// EnableMutationAudit makes the controller write a JSONL record of every mutating API call
+// it performs to the given sink. It must be called before Run.
+func (dc *DeploymentController) EnableMutationAudit(sink io.Writer) {
+	dc.mutationAudit = newMutationAudit(sink)
+}
+
+// auditMutation records a mutating API call in the mutation audit log, if one is enabled.
+func (dc *DeploymentController) auditMutation(kind, namespace, name, verb, summary, reason string) {
+	if dc.mutationAudit == nil {
+		return
+	}
+	dc.mutationAudit.record(mutationAuditEntry{
+		Time:      metav1.Now(),
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Verb:      verb,
+		Summary:   summary,
+		Reason:    reason,
+	})
+}
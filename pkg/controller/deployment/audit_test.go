@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMutationAuditRecordsScaling(t *testing.T) {
+	var buf bytes.Buffer
+
+	d := newDeployment("foo", 3, nil, nil, nil, map[string]string{"foo": "bar"})
+	rs := newReplicaSet(d, "foo-1", 1)
+	dc := &DeploymentController{
+		client:        &fake.Clientset{},
+		eventRecorder: &record.FakeRecorder{},
+	}
+	dc.EnableMutationAudit(&buf)
+
+	if _, _, err := dc.scaleReplicaSetAndRecordEvent(context.TODO(), rs, 3, d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var entry mutationAuditEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("couldn't decode audit entry %q: %v", buf.String(), err)
+	}
+	if entry.Kind != "ReplicaSet" || entry.Verb != "patch" || entry.Name != rs.Name {
+		t.Errorf("unexpected audit entry: %+v", entry)
+	}
+	if entry.Summary != "replicas 1 -> 3" {
+		t.Errorf("unexpected audit summary: %q", entry.Summary)
+	}
+}
+
+func TestMutationAuditDisabledByDefault(t *testing.T) {
+	d := newDeployment("foo", 3, nil, nil, nil, map[string]string{"foo": "bar"})
+	rs := newReplicaSet(d, "foo-1", 1)
+	dc := &DeploymentController{
+		client:        &fake.Clientset{},
+		eventRecorder: &record.FakeRecorder{},
+	}
+
+	// Must not panic without an audit sink configured.
+	if _, _, err := dc.scaleReplicaSetAndRecordEvent(context.TODO(), rs, 3, d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
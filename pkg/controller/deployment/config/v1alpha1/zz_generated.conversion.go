@@ -21,6 +21,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	unsafe "unsafe"
+
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	conversion "k8s.io/apimachinery/pkg/conversion"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -61,12 +63,46 @@ func RegisterConversions(s *runtime.Scheme) error {
 func autoConvert_v1alpha1_DeploymentControllerConfiguration_To_config_DeploymentControllerConfiguration(in *v1alpha1.DeploymentControllerConfiguration, out *config.DeploymentControllerConfiguration, s conversion.Scope) error {
 	out.ConcurrentDeploymentSyncs = in.ConcurrentDeploymentSyncs
 	out.DeploymentControllerSyncPeriod = in.DeploymentControllerSyncPeriod
+	out.MutationAuditLogPath = in.MutationAuditLogPath
+	out.NodeUnavailabilityThresholdPercent = in.NodeUnavailabilityThresholdPercent
+	out.RollbackWebhookURL = in.RollbackWebhookURL
+	out.AnnotationPropagationInclude = *(*[]string)(unsafe.Pointer(&in.AnnotationPropagationInclude))
+	out.AnnotationPropagationExclude = *(*[]string)(unsafe.Pointer(&in.AnnotationPropagationExclude))
+	out.DisableOrphanAdoption = in.DisableOrphanAdoption
+	out.ProgressRecheckMinInterval = in.ProgressRecheckMinInterval
+	out.ProgressRecheckMaxInterval = in.ProgressRecheckMaxInterval
+	out.ServerSideApplyStatus = in.ServerSideApplyStatus
+	out.StatusWriteMinInterval = in.StatusWriteMinInterval
+	out.ShardIndex = in.ShardIndex
+	out.ShardCount = in.ShardCount
+	out.WatchLabelSelector = in.WatchLabelSelector
+	out.QueueBaseDelay = in.QueueBaseDelay
+	out.QueueMaxDelay = in.QueueMaxDelay
+	out.QueueMaxRetries = in.QueueMaxRetries
+	out.MaxConcurrentDeploymentSyncs = in.MaxConcurrentDeploymentSyncs
 	return nil
 }
 
 func autoConvert_config_DeploymentControllerConfiguration_To_v1alpha1_DeploymentControllerConfiguration(in *config.DeploymentControllerConfiguration, out *v1alpha1.DeploymentControllerConfiguration, s conversion.Scope) error {
 	out.ConcurrentDeploymentSyncs = in.ConcurrentDeploymentSyncs
 	out.DeploymentControllerSyncPeriod = in.DeploymentControllerSyncPeriod
+	out.MutationAuditLogPath = in.MutationAuditLogPath
+	out.NodeUnavailabilityThresholdPercent = in.NodeUnavailabilityThresholdPercent
+	out.RollbackWebhookURL = in.RollbackWebhookURL
+	out.AnnotationPropagationInclude = *(*[]string)(unsafe.Pointer(&in.AnnotationPropagationInclude))
+	out.AnnotationPropagationExclude = *(*[]string)(unsafe.Pointer(&in.AnnotationPropagationExclude))
+	out.DisableOrphanAdoption = in.DisableOrphanAdoption
+	out.ProgressRecheckMinInterval = in.ProgressRecheckMinInterval
+	out.ProgressRecheckMaxInterval = in.ProgressRecheckMaxInterval
+	out.ServerSideApplyStatus = in.ServerSideApplyStatus
+	out.StatusWriteMinInterval = in.StatusWriteMinInterval
+	out.ShardIndex = in.ShardIndex
+	out.ShardCount = in.ShardCount
+	out.WatchLabelSelector = in.WatchLabelSelector
+	out.QueueBaseDelay = in.QueueBaseDelay
+	out.QueueMaxDelay = in.QueueMaxDelay
+	out.QueueMaxRetries = in.QueueMaxRetries
+	out.MaxConcurrentDeploymentSyncs = in.MaxConcurrentDeploymentSyncs
 	return nil
 }
 
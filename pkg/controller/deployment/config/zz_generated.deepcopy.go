@@ -24,6 +24,16 @@ package config
 func (in *DeploymentControllerConfiguration) DeepCopyInto(out *DeploymentControllerConfiguration) {
 	*out = *in
 	out.DeploymentControllerSyncPeriod = in.DeploymentControllerSyncPeriod
+	if in.AnnotationPropagationInclude != nil {
+		in, out := &in.AnnotationPropagationInclude, &out.AnnotationPropagationInclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AnnotationPropagationExclude != nil {
+		in, out := &in.AnnotationPropagationExclude, &out.AnnotationPropagationExclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -22,33 +22,45 @@ package deployment
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"math/rand"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/klog/v2"
 
+	"golang.org/x/time/rate"
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	appsinformers "k8s.io/client-go/informers/apps/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
+	policyinformers "k8s.io/client-go/informers/policy/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/component-base/metrics/prometheus/ratelimiter"
 	"k8s.io/kubernetes/pkg/controller"
 	"k8s.io/kubernetes/pkg/controller/deployment/util"
+	utiltrace "k8s.io/utils/trace"
 )
 
 const (
@@ -63,6 +75,40 @@ const (
 // controllerKind contains the schema.GroupVersionKind for this controller type.
 var controllerKind = apps.SchemeGroupVersion.WithKind("Deployment")
 
+// replicaSetControllerUIDIndex indexes replica sets by the UID of their controlling
+// owner, so owned replica sets can be looked up without listing the whole namespace.
+const replicaSetControllerUIDIndex = "controllerUID"
+
+// replicaSetControllerUIDIndexFunc implements replicaSetControllerUIDIndex.
+func replicaSetControllerUIDIndexFunc(obj interface{}) ([]string, error) {
+	rs, ok := obj.(*apps.ReplicaSet)
+	if !ok {
+		return nil, nil
+	}
+	controllerRef := metav1.GetControllerOf(rs)
+	if controllerRef == nil {
+		return nil, nil
+	}
+	return []string{string(controllerRef.UID)}, nil
+}
+
+// podControllerUIDIndex indexes pods by the UID of their controlling owner, so the pods
+// of a replica set can be looked up without listing by selector.
+const podControllerUIDIndex = "podControllerUID"
+
+// podControllerUIDIndexFunc implements podControllerUIDIndex.
+func podControllerUIDIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	controllerRef := metav1.GetControllerOf(pod)
+	if controllerRef == nil {
+		return nil, nil
+	}
+	return []string{string(controllerRef.UID)}, nil
+}
+
 // DeploymentController is responsible for synchronizing Deployment objects stored
 // in the system with actual running replica sets and pods.
 type DeploymentController struct {
@@ -72,16 +118,27 @@ type DeploymentController struct {
 	eventRecorder record.EventRecorder
 
 	// To allow injection of syncDeployment for testing.
-	syncHandler func(dKey string) error
+	syncHandler func(ctx context.Context, dKey string) error
 	// used for unit testing
 	enqueueDeployment func(deployment *apps.Deployment)
+	// enqueueObjectDriven is used by the replica set and pod event handlers; unlike
+	// enqueueDeployment it dampens per-key bursts. Also injectable for unit testing.
+	enqueueObjectDriven func(deployment *apps.Deployment)
 
 	// dLister can list/get deployments from the shared informer's store
 	dLister appslisters.DeploymentLister
 	// rsLister can list/get replica sets from the shared informer's store
 	rsLister appslisters.ReplicaSetLister
+	// rsIndexer gives direct access to the replica set store and its owner-UID index.
+	rsIndexer cache.Indexer
+	// podIndexer gives direct access to the pod store and its owner-UID index.
+	podIndexer cache.Indexer
 	// podLister can list/get pods from the shared informer's store
 	podLister corelisters.PodLister
+	// pdbLister can list/get pod disruption budgets from the shared informer's store
+	pdbLister policylisters.PodDisruptionBudgetLister
+	// nodeLister can list/get nodes from the shared informer's store
+	nodeLister corelisters.NodeLister
 
 	// dListerSynced returns true if the Deployment store has been synced at least once.
 	// Added as a member to the struct to allow injection for testing.
@@ -92,32 +149,198 @@ type DeploymentController struct {
 	// podListerSynced returns true if the pod store has been synced at least once.
 	// Added as a member to the struct to allow injection for testing.
 	podListerSynced cache.InformerSynced
+	// pdbListerSynced returns true if the PodDisruptionBudget store has been synced at
+	// least once. Added as a member to the struct to allow injection for testing.
+	pdbListerSynced cache.InformerSynced
+	// nodeListerSynced returns true if the node store has been synced at least once.
+	// Added as a member to the struct to allow injection for testing.
+	nodeListerSynced cache.InformerSynced
 
 	// Deployments that need to be synced
 	queue workqueue.RateLimitingInterface
+	// Deployments that need attention soon — close to their progress deadline or
+	// without minimum availability — served by a dedicated worker ahead of routine
+	// resyncs. See deploymentNeedsUrgentSync.
+	urgentQueue workqueue.RateLimitingInterface
+	// Deployments whose revision history needs cleaning, handled by a dedicated
+	// low-priority worker so bulk deletions never delay rollout syncs.
+	cleanupQueue workqueue.RateLimitingInterface
+
+	// inFlight guards against syncing the same key concurrently from the regular and
+	// the urgent queue.
+	inFlightMu sync.Mutex
+	inFlight   map[string]bool
+
+	// rsExpectations suppresses the informer echo of the controller's own replica set
+	// creates and scale patches.
+	rsExpectations *rsMutationExpectations
+
+	// mutationAudit, when non-nil, records every mutating API call the controller
+	// performs. See EnableMutationAudit.
+	mutationAudit *mutationAudit
+
+	// objectEnqueueTimes remembers when each deployment key was last enqueued because of
+	// an owned pod or replica set event, to dampen event storms from pod churn;
+	// objectEnqueueDeferred marks keys that already have a deferred add scheduled, so a
+	// storm of events collapses into exactly one deferred sync instead of piling up
+	// timers.
+	objectEnqueueMu       sync.Mutex
+	objectEnqueueTimes    map[string]time.Time
+	objectEnqueueDeferred map[string]bool
+
+	// progressSamples remembers the last observed availability per deployment for
+	// estimating rollout completion times.
+	progressSampleMu sync.Mutex
+	progressSamples  map[string]progressSample
+
+	// deadlineWarnings remembers which escalation thresholds have already been warned
+	// about per deployment while it approaches its progress deadline.
+	deadlineWarningMu sync.Mutex
+	deadlineWarnings  map[string]deadlineWarningState
+
+	// nodeHoldThresholdPercent, when positive, holds rollout progression while more than
+	// this percentage of nodes is unschedulable or not ready. See SetNodeHoldThreshold.
+	nodeHoldThresholdPercent int32
+
+	// analysisProvider, when non-nil and the DeploymentRolloutAnalysis feature gate is
+	// enabled, is consulted between rollout steps. See SetAnalysisProvider.
+	analysisProvider AnalysisProvider
+
+	// rollbackWebhookURL, when set, receives a JSON POST for every automatic rollback
+	// the controller performs. See SetRollbackWebhook.
+	rollbackWebhookURL string
+
+	// disableOrphanAdoption, when true, keeps the controller from adopting orphaned
+	// replica sets for any deployment. See SetDisableOrphanAdoption.
+	disableOrphanAdoption bool
+
+	// progressRecheckMinInterval/progressRecheckMaxInterval clamp how soon and how late
+	// a potentially stuck deployment is requeued for a progress check. See
+	// SetProgressRecheckIntervals.
+	progressRecheckMinInterval time.Duration
+	progressRecheckMaxInterval time.Duration
+
+	// applyStatusUpdates, when true, writes deployment status with server-side apply
+	// under a dedicated field manager instead of UpdateStatus. See
+	// SetServerSideApplyForStatus.
+	applyStatusUpdates bool
+
+	// statusWriteMinInterval, when positive, bounds how often status is written per
+	// deployment; writes arriving sooner are deferred to a requeue, which recomputes
+	// and coalesces them. See SetStatusWriteMinInterval.
+	statusWriteMinInterval time.Duration
+	statusWriteMu          sync.Mutex
+	statusWriteTimes       map[string]time.Time
+
+	// shardIndex/shardCount restrict this instance to a consistent-hash shard of the
+	// namespaces. See SetShard.
+	shardIndex int32
+	shardCount int32
+
+	// maxSyncRetries caps how often a failing deployment sync is retried before its key
+	// is dropped from the queue; zero means the built-in default. See
+	// SetQueueRateLimiter.
+	maxSyncRetries int
+	// conflictBackoff and parkBackoff are the per-item backoffs of the error-class-aware
+	// retry policy; their requeue counts add to the queue's so classified retries still
+	// obey the retry cap.
+	conflictBackoff workqueue.RateLimiter
+	parkBackoff     workqueue.RateLimiter
+
+	// maxWorkers, when larger than the worker count passed to Run, lets the controller
+	// grow its worker pool while the queue stays deep. See SetMaxWorkers.
+	maxWorkers int
+
+	// recentSyncNamespaces remembers the namespaces of recently processed syncs, for
+	// round-robin fairness between namespaces under backlog.
+	nsFairnessMu         sync.Mutex
+	recentSyncNamespaces []namespaceSyncRecord
+
+	// lastSuccessfulSync is when a sync last finished without error, for health
+	// reporting.
+	lastSyncMu         sync.Mutex
+	lastSuccessfulSync time.Time
+
+	// lastSyncErrors keeps the most recent sync error per deployment for the debug
+	// endpoint; entries are cleared on success and on deletion.
+	lastSyncErrMu  sync.Mutex
+	lastSyncErrors map[string]string
+
+	// draining is set when shutdown starts; workers then stop picking up new work so
+	// only genuinely in-flight syncs are waited for.
+	draining int32
+
+	// pendingRequeues remembers the earliest pending enqueueAfter deadline per key, so
+	// overlapping requeue timers are merged instead of piling up.
+	pendingRequeueMu sync.Mutex
+	pendingRequeues  map[string]time.Time
+
+	// clock, when set, replaces the wall clock in deadline and age computations so
+	// tests can fast-forward time deterministically. See SetClock.
+	clock clock.Clock
+
+	// throttleTimes remembers recent apiserver throttling errors; enough of them within
+	// the window opens a circuit breaker that lengthens requeues and sheds
+	// object-driven resyncs until the pressure subsides.
+	throttleMu    sync.Mutex
+	throttleTimes []time.Time
+}
+
+// namespaceSyncRecord is one entry of the fairness window.
+type namespaceSyncRecord struct {
+	namespace string
+	at        time.Time
+}
+
+// NewDeploymentController creates a new DeploymentController recording events through a
+// broadcaster against the client's event sink.
+func NewDeploymentController(dInformer appsinformers.DeploymentInformer, rsInformer appsinformers.ReplicaSetInformer, podInformer coreinformers.PodInformer, pdbInformer policyinformers.PodDisruptionBudgetInformer, nodeInformer coreinformers.NodeInformer, client clientset.Interface) (*DeploymentController, error) {
+	return NewDeploymentControllerWithEventRecorder(dInformer, rsInformer, podInformer, pdbInformer, nodeInformer, client, nil)
 }
 
-// NewDeploymentController creates a new DeploymentController.
-func NewDeploymentController(dInformer appsinformers.DeploymentInformer, rsInformer appsinformers.ReplicaSetInformer, podInformer coreinformers.PodInformer, client clientset.Interface) (*DeploymentController, error) {
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartStructuredLogging(0)
-	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+// NewDeploymentControllerWithEventRecorder is NewDeploymentController with a
+// caller-provided event recorder, enabling event deduplication layers and test capture
+// of emitted events. A nil recorder falls back to the default broadcaster against the
+// client's event sink.
+func NewDeploymentControllerWithEventRecorder(dInformer appsinformers.DeploymentInformer, rsInformer appsinformers.ReplicaSetInformer, podInformer coreinformers.PodInformer, pdbInformer policyinformers.PodDisruptionBudgetInformer, nodeInformer coreinformers.NodeInformer, client clientset.Interface, recorder record.EventRecorder) (*DeploymentController, error) {
+	if recorder == nil {
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartStructuredLogging(0)
+		eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+		recorder = eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "deployment-controller"})
+	}
 
 	if client != nil && client.CoreV1().RESTClient().GetRateLimiter() != nil {
 		if err := ratelimiter.RegisterMetricAndTrackRateLimiterUsage("deployment_controller", client.CoreV1().RESTClient().GetRateLimiter()); err != nil {
 			return nil, err
 		}
 	}
+	registerMetrics()
 	dc := &DeploymentController{
-		client:        client,
-		eventRecorder: eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "deployment-controller"}),
-		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment"),
+		client:                client,
+		eventRecorder:         recorder,
+		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment"),
+		urgentQueue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment-urgent"),
+		cleanupQueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment-cleanup"),
+		inFlight:              make(map[string]bool),
+		lastSyncErrors:        make(map[string]string),
+		pendingRequeues:       make(map[string]time.Time),
+		rsExpectations:        newRSMutationExpectations(),
+		objectEnqueueTimes:    make(map[string]time.Time),
+		objectEnqueueDeferred: make(map[string]bool),
+		progressSamples:       make(map[string]progressSample),
+		deadlineWarnings:      make(map[string]deadlineWarningState),
+		statusWriteTimes:      make(map[string]time.Time),
 	}
 	dc.rsControl = controller.RealRSControl{
 		KubeClient: client,
 		Recorder:   dc.eventRecorder,
 	}
 
+	// TODO: install cache transform functions that strip managedFields (and other heavy
+	// metadata) from the cached Deployments, ReplicaSets and Pods once the vendored
+	// client-go provides SharedInformer.SetTransform; the version vendored here predates
+	// it, so the memory savings cannot be realized in this tree yet.
 	dInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    dc.addDeployment,
 		UpdateFunc: dc.updateDeployment,
@@ -129,39 +352,141 @@ func NewDeploymentController(dInformer appsinformers.DeploymentInformer, rsInfor
 		UpdateFunc: dc.updateReplicaSet,
 		DeleteFunc: dc.deleteReplicaSet,
 	})
+	if err := rsInformer.Informer().AddIndexers(cache.Indexers{replicaSetControllerUIDIndex: replicaSetControllerUIDIndexFunc}); err != nil {
+		return nil, err
+	}
+	dc.rsIndexer = rsInformer.Informer().GetIndexer()
+	if err := podInformer.Informer().AddIndexers(cache.Indexers{podControllerUIDIndex: podControllerUIDIndexFunc}); err != nil {
+		return nil, err
+	}
+	dc.podIndexer = podInformer.Informer().GetIndexer()
+	// TODO: offer a metadata-only pod informer mode. Most pod usage here only needs
+	// existence, ownership and a few status fields (phase, readiness, container waiting
+	// reasons); backing podLister with a metadata informer plus a transform keeping
+	// those fields would cut cache memory substantially on very large clusters. Like
+	// the cache transforms above this needs a newer client-go than this tree vendors:
+	// metadata informers alone cannot carry the status fields the Recreate and
+	// crash-loop paths depend on.
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		DeleteFunc: dc.deletePod,
 	})
 
 	dc.syncHandler = dc.syncDeployment
 	dc.enqueueDeployment = dc.enqueue
+	dc.enqueueObjectDriven = dc.enqueueObjectDampened
 
 	dc.dLister = dInformer.Lister()
 	dc.rsLister = rsInformer.Lister()
 	dc.podLister = podInformer.Lister()
+	dc.pdbLister = pdbInformer.Lister()
+	dc.nodeLister = nodeInformer.Lister()
 	dc.dListerSynced = dInformer.Informer().HasSynced
 	dc.rsListerSynced = rsInformer.Informer().HasSynced
 	dc.podListerSynced = podInformer.Informer().HasSynced
+	dc.pdbListerSynced = pdbInformer.Informer().HasSynced
+	dc.nodeListerSynced = nodeInformer.Informer().HasSynced
 	return dc, nil
 }
 
 // Run begins watching and syncing.
-func (dc *DeploymentController) Run(workers int, stopCh <-chan struct{}) {
+func (dc *DeploymentController) Run(ctx context.Context, workers int) {
 	defer utilruntime.HandleCrash()
 	defer dc.queue.ShutDown()
+	defer dc.urgentQueue.ShutDown()
+	defer dc.cleanupQueue.ShutDown()
 
 	klog.InfoS("Starting controller", "controller", "deployment")
 	defer klog.InfoS("Shutting down controller", "controller", "deployment")
 
-	if !cache.WaitForNamedCacheSync("deployment", stopCh, dc.dListerSynced, dc.rsListerSynced, dc.podListerSynced) {
+	if !cache.WaitForNamedCacheSync("deployment", ctx.Done(), dc.dListerSynced, dc.rsListerSynced, dc.podListerSynced, dc.pdbListerSynced, dc.nodeListerSynced) {
 		return
 	}
 
+	// Prime the queue from the lister so deployments that changed while the controller
+	// was down are reconciled promptly and predictably, instead of waiting for resync
+	// or update events.
+	dc.primeQueue()
+
+	// Workers run on a context detached from the stop signal so in-flight syncs can
+	// finish during the drain below instead of having their API calls cancelled
+	// mid-rollout when leadership moves.
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
 	for i := 0; i < workers; i++ {
-		go wait.Until(dc.worker, time.Second, stopCh)
+		go wait.UntilWithContext(workerCtx, dc.worker, time.Second)
+	}
+	if dc.maxWorkers > workers {
+		go dc.scaleWorkers(workerCtx, workers)
 	}
+	go wait.UntilWithContext(workerCtx, dc.urgentWorker, time.Second)
+	go wait.UntilWithContext(workerCtx, dc.cleanupWorker, time.Second)
+	go wait.Until(dc.sampleQueueDepths, 10*time.Second, ctx.Done())
+	go wait.Until(dc.sweepAbandonedReplicaSets, abandonedRSSweepPeriod, ctx.Done())
 
-	<-stopCh
+	<-ctx.Done()
+	dc.drainWorkers(cancelWorkers)
+}
+
+// warmStartSpread is the window over which the warm-start enqueues are jittered, so a
+// restart doesn't slam the apiserver with one burst of syncs.
+const warmStartSpread = 30 * time.Second
+
+// primeQueue enqueues all known deployments of this instance's shard once, spread with
+// jitter over the warm-start window.
+func (dc *DeploymentController) primeQueue() {
+	deployments, err := dc.dLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list deployments for the warm start: %v", err))
+		return
+	}
+	for _, d := range deployments {
+		if !dc.ownsNamespace(d.Namespace) {
+			continue
+		}
+		key, err := controller.KeyFunc(d)
+		if err != nil {
+			continue
+		}
+		dc.queue.AddAfter(key, time.Duration(rand.Int63n(int64(warmStartSpread))))
+	}
+	klog.V(2).InfoS("Primed the deployment queue from the lister", "deployments", len(deployments), "spread", warmStartSpread)
+}
+
+// workerDrainTimeout bounds how long shutdown waits for in-flight syncs to finish.
+const workerDrainTimeout = 10 * time.Second
+
+// drainWorkers performs a graceful shutdown: workers stop dispatching new work,
+// in-flight syncs get up to workerDrainTimeout to finish, and whatever work remains
+// unprocessed is logged before the workers are cancelled.
+func (dc *DeploymentController) drainWorkers(cancelWorkers context.CancelFunc) {
+	// ShutDown alone keeps serving the backlog until the queues are empty; the draining
+	// flag is what actually stops new syncs from starting.
+	atomic.StoreInt32(&dc.draining, 1)
+	dc.queue.ShutDown()
+	dc.urgentQueue.ShutDown()
+	dc.cleanupQueue.ShutDown()
+
+	deadline := time.Now().Add(workerDrainTimeout)
+	for time.Now().Before(deadline) {
+		dc.inFlightMu.Lock()
+		remaining := len(dc.inFlight)
+		dc.inFlightMu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	dc.inFlightMu.Lock()
+	for key := range dc.inFlight {
+		klog.InfoS("Abandoning in-flight deployment sync on shutdown", "deployment", key)
+	}
+	dc.inFlightMu.Unlock()
+	if unprocessed := dc.queue.Len() + dc.urgentQueue.Len() + dc.cleanupQueue.Len(); unprocessed > 0 {
+		klog.InfoS("Leaving unprocessed work behind on shutdown", "keys", unprocessed)
+	}
+	cancelWorkers()
 }
 
 func (dc *DeploymentController) addDeployment(obj interface{}) {
@@ -195,6 +520,31 @@ func (dc *DeploymentController) deleteDeployment(obj interface{}) {
 	}
 	klog.V(4).InfoS("Deleting deployment", "deployment", klog.KObj(d))
 	dc.enqueueDeployment(d)
+
+	// Forget any enqueue dampening and progress tracking state kept for the deleted
+	// deployment.
+	if key, err := controller.KeyFunc(d); err == nil {
+		dc.objectEnqueueMu.Lock()
+		delete(dc.objectEnqueueTimes, key)
+		delete(dc.objectEnqueueDeferred, key)
+		dc.objectEnqueueMu.Unlock()
+		dc.progressSampleMu.Lock()
+		delete(dc.progressSamples, key)
+		dc.progressSampleMu.Unlock()
+		dc.deadlineWarningMu.Lock()
+		delete(dc.deadlineWarnings, key)
+		dc.deadlineWarningMu.Unlock()
+		dc.statusWriteMu.Lock()
+		delete(dc.statusWriteTimes, key)
+		dc.statusWriteMu.Unlock()
+		dc.lastSyncErrMu.Lock()
+		delete(dc.lastSyncErrors, key)
+		dc.lastSyncErrMu.Unlock()
+		dc.pendingRequeueMu.Lock()
+		delete(dc.pendingRequeues, key)
+		dc.pendingRequeueMu.Unlock()
+		dc.forgetClassifiedRetries(key)
+	}
 }
 
 // addReplicaSet enqueues the deployment that manages a ReplicaSet when the ReplicaSet is created.
@@ -214,8 +564,14 @@ func (dc *DeploymentController) addReplicaSet(obj interface{}) {
 		if d == nil {
 			return
 		}
+		// This controller created the replica set moments ago; the creating sync
+		// already acted on it.
+		if dc.rsExpectations != nil && dc.rsExpectations.observe(rs.UID) {
+			klog.V(4).InfoS("ReplicaSet add was caused by this controller, not requeueing", "replicaSet", klog.KObj(rs))
+			return
+		}
 		klog.V(4).InfoS("ReplicaSet added", "replicaSet", klog.KObj(rs))
-		dc.enqueueDeployment(d)
+		dc.enqueueObjectDriven(d)
 		return
 	}
 
@@ -227,8 +583,18 @@ func (dc *DeploymentController) addReplicaSet(obj interface{}) {
 	}
 	klog.V(4).InfoS("Orphan ReplicaSet added", "replicaSet", klog.KObj(rs))
 	for _, d := range ds {
-		dc.enqueueDeployment(d)
+		dc.enqueueObjectDriven(d)
+	}
+}
+
+// controllerRefsEqual compares two controller references field-wise. The handlers run
+// for every replica set update in the cluster, so this avoids reflect.DeepEqual on the
+// hot path; the identifying fields decide equality.
+func controllerRefsEqual(a, b *metav1.OwnerReference) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return a.UID == b.UID && a.Name == b.Name && a.Kind == b.Kind && a.APIVersion == b.APIVersion
 }
 
 // getDeploymentsForReplicaSet returns a list of Deployments that potentially
@@ -266,11 +632,11 @@ func (dc *DeploymentController) updateReplicaSet(old, cur interface{}) {
 
 	curControllerRef := metav1.GetControllerOf(curRS)
 	oldControllerRef := metav1.GetControllerOf(oldRS)
-	controllerRefChanged := !reflect.DeepEqual(curControllerRef, oldControllerRef)
+	controllerRefChanged := !controllerRefsEqual(curControllerRef, oldControllerRef)
 	if controllerRefChanged && oldControllerRef != nil {
 		// The ControllerRef was changed. Sync the old controller, if any.
 		if d := dc.resolveControllerRef(oldRS.Namespace, oldControllerRef); d != nil {
-			dc.enqueueDeployment(d)
+			dc.enqueueObjectDriven(d)
 		}
 	}
 
@@ -280,14 +646,19 @@ func (dc *DeploymentController) updateReplicaSet(old, cur interface{}) {
 		if d == nil {
 			return
 		}
+		// Skip the echo of this controller's own scale patches and annotation syncs.
+		if !controllerRefChanged && dc.rsExpectations != nil && dc.rsExpectations.observe(curRS.UID) {
+			klog.V(4).InfoS("ReplicaSet update was caused by this controller, not requeueing", "replicaSet", klog.KObj(curRS))
+			return
+		}
 		klog.V(4).InfoS("ReplicaSet updated", "replicaSet", klog.KObj(curRS))
-		dc.enqueueDeployment(d)
+		dc.enqueueObjectDriven(d)
 		return
 	}
 
 	// Otherwise, it's an orphan. If anything changed, sync matching controllers
 	// to see if anyone wants to adopt it now.
-	labelChanged := !reflect.DeepEqual(curRS.Labels, oldRS.Labels)
+	labelChanged := !labels.Equals(curRS.Labels, oldRS.Labels)
 	if labelChanged || controllerRefChanged {
 		ds := dc.getDeploymentsForReplicaSet(curRS)
 		if len(ds) == 0 {
@@ -295,7 +666,7 @@ func (dc *DeploymentController) updateReplicaSet(old, cur interface{}) {
 		}
 		klog.V(4).InfoS("Orphan ReplicaSet updated", "replicaSet", klog.KObj(curRS))
 		for _, d := range ds {
-			dc.enqueueDeployment(d)
+			dc.enqueueObjectDriven(d)
 		}
 	}
 }
@@ -332,8 +703,11 @@ func (dc *DeploymentController) deleteReplicaSet(obj interface{}) {
 	if d == nil {
 		return
 	}
+	if dc.rsExpectations != nil {
+		dc.rsExpectations.forget(rs.UID)
+	}
 	klog.V(4).InfoS("ReplicaSet deleted", "replicaSet", klog.KObj(rs))
-	dc.enqueueDeployment(d)
+	dc.enqueueObjectDriven(d)
 }
 
 // deletePod will enqueue a Recreate Deployment once all of its pods have stopped running.
@@ -358,8 +732,9 @@ func (dc *DeploymentController) deletePod(obj interface{}) {
 	}
 	klog.V(4).InfoS("Pod deleted", "pod", klog.KObj(pod))
 	if d := dc.getDeploymentForPod(pod); d != nil && d.Spec.Strategy.Type == apps.RecreateDeploymentStrategyType {
-		// Sync if this Deployment now has no more Pods.
-		rsList, err := util.ListReplicaSets(d, util.RsListFromClient(dc.client.AppsV1()))
+		// Sync if this Deployment now has no more Pods. Work entirely from the listers:
+		// an apiserver LIST per deleted pod turns mass deletions into list storms.
+		rsList, err := dc.listOwnedReplicaSets(d)
 		if err != nil {
 			return
 		}
@@ -372,7 +747,7 @@ func (dc *DeploymentController) deletePod(obj interface{}) {
 			numPods += len(podList)
 		}
 		if numPods == 0 {
-			dc.enqueueDeployment(d)
+			dc.enqueueObjectDriven(d)
 		}
 	}
 }
@@ -385,10 +760,40 @@ func (dc *DeploymentController) enqueue(deployment *apps.Deployment) {
 		return
 	}
 
+	// Deployments outside this instance's namespace shard belong to another instance.
+	if !dc.ownsNamespace(deployment.Namespace) {
+		return
+	}
+	// Deployments in trouble skip the line ahead of routine resyncs: the urgent queue
+	// is served by its own worker. The key also stays on the regular queue so that,
+	// when many deployments are in trouble at once, the whole worker pool can serve
+	// them instead of serializing on the urgent worker; the in-flight guard keeps the
+	// two queues from syncing the same key concurrently.
+	if dc.urgentQueue != nil && deploymentNeedsUrgentSync(deployment) {
+		dc.urgentQueue.Add(key)
+	}
 	// 이 키를 진짜 큐에 넣는다.
 	dc.queue.Add(key)
 }
 
+// deploymentNeedsUrgentSync reports whether the deployment should be synced ahead of
+// routine work: it lost minimum availability, or it has burned at least 80% of its
+// progress deadline without progress.
+func deploymentNeedsUrgentSync(deployment *apps.Deployment) bool {
+	if available := util.GetDeploymentCondition(deployment.Status, apps.DeploymentAvailable); available != nil && available.Status == v1.ConditionFalse {
+		return true
+	}
+	if !util.HasProgressDeadline(deployment) {
+		return false
+	}
+	progressing := util.GetDeploymentCondition(deployment.Status, apps.DeploymentProgressing)
+	if progressing == nil || progressing.Reason == util.TimedOutReason || progressing.Reason == util.NewRSAvailableReason {
+		return false
+	}
+	deadline := time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second
+	return time.Since(progressing.LastUpdateTime.Time) >= deadline*8/10
+}
+
 func (dc *DeploymentController) enqueueRateLimited(deployment *apps.Deployment) {
 	key, err := controller.KeyFunc(deployment)
 	if err != nil {
@@ -399,7 +804,59 @@ func (dc *DeploymentController) enqueueRateLimited(deployment *apps.Deployment)
 	dc.queue.AddRateLimited(key)
 }
 
-// enqueueAfter will enqueue a deployment after the provided amount of time.
+// objectEnqueueDampeningPeriod is the minimum distance between two enqueues of the same
+// deployment caused by owned pod or replica set events. User spec changes are never
+// dampened.
+const objectEnqueueDampeningPeriod = time.Second
+
+// enqueueObjectDampened enqueues a deployment for an owned-object event. Bursts of such
+// events for the same key (e.g. pod churn of a crash-looping deployment) are coalesced:
+// if the key was enqueued this way within the dampening period, the add is deferred to
+// the end of the period instead of keeping the key perpetually hot.
+func (dc *DeploymentController) enqueueObjectDampened(deployment *apps.Deployment) {
+	key, err := controller.KeyFunc(deployment)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %#v: %v", deployment, err))
+		return
+	}
+
+	// While the apiserver is throttling us, shed object-driven resyncs entirely; the
+	// deferred adds and spec-change enqueues keep correctness.
+	if dc.throttled() {
+		dampenedEnqueuesTotal.Inc()
+		dc.queue.AddAfter(key, throttleBackoff)
+		return
+	}
+
+	now := time.Now()
+	dc.objectEnqueueMu.Lock()
+	last, seen := dc.objectEnqueueTimes[key]
+	delay := objectEnqueueDampeningPeriod - now.Sub(last)
+	if !seen || delay <= 0 {
+		dc.objectEnqueueTimes[key] = now
+		delete(dc.objectEnqueueDeferred, key)
+		dc.objectEnqueueMu.Unlock()
+		dc.enqueueDeployment(deployment)
+		return
+	}
+	// Within the dampening window, all further events coalesce into the single add
+	// already deferred to the end of the window.
+	if dc.objectEnqueueDeferred[key] {
+		dc.objectEnqueueMu.Unlock()
+		dampenedEnqueuesTotal.Inc()
+		return
+	}
+	dc.objectEnqueueDeferred[key] = true
+	dc.objectEnqueueMu.Unlock()
+
+	dampenedEnqueuesTotal.Inc()
+	dc.queue.AddAfter(key, delay)
+}
+
+// enqueueAfter will enqueue a deployment after the provided amount of time. Overlapping
+// requeue deadlines for the same key (progress checks, bake times, freeze-window ends,
+// escalation warnings) are merged: a wakeup is only scheduled when no earlier one is
+// still pending, avoiding timer buildup and duplicate wakeups.
 func (dc *DeploymentController) enqueueAfter(deployment *apps.Deployment, after time.Duration) {
 	key, err := controller.KeyFunc(deployment)
 	if err != nil {
@@ -407,6 +864,21 @@ func (dc *DeploymentController) enqueueAfter(deployment *apps.Deployment, after
 		return
 	}
 
+	if dc.pendingRequeues != nil {
+		now := time.Now()
+		target := now.Add(after)
+		dc.pendingRequeueMu.Lock()
+		pending, ok := dc.pendingRequeues[key]
+		if ok && pending.After(now) && !pending.After(target) {
+			// An earlier (or equal) wakeup is already pending; it will resync the
+			// deployment, which reevaluates all deadlines anyway.
+			dc.pendingRequeueMu.Unlock()
+			return
+		}
+		dc.pendingRequeues[key] = target
+		dc.pendingRequeueMu.Unlock()
+	}
+
 	dc.queue.AddAfter(key, after)
 }
 
@@ -459,32 +931,466 @@ func (dc *DeploymentController) resolveControllerRef(namespace string, controlle
 	return d
 }
 
+const (
+	// abandonedRSSweepPeriod is how often the controller sweeps for replica sets whose
+	// owning deployment no longer exists.
+	abandonedRSSweepPeriod = 10 * time.Minute
+	// abandonedRSMinAge guards the sweep against informer lag: only replica sets at
+	// least this old are considered abandoned.
+	abandonedRSMinAge = 10 * time.Minute
+)
+
+// ownerRefIsDeployment reports whether the controller reference points at an apps group
+// Deployment (accepting the legacy extensions group). Matching by kind alone would make
+// the abandoned sweep delete replica sets owned by a same-kind object from a third-party
+// API group.
+func ownerRefIsDeployment(controllerRef *metav1.OwnerReference) bool {
+	if controllerRef.Kind != controllerKind.Kind {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(controllerRef.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == controllerKind.Group || gv.Group == "extensions"
+}
+
+// sweepAbandonedReplicaSets garbage-collects zero-replica replica sets whose controller
+// reference points at a deployment that no longer exists (e.g. after a failed cascading
+// delete), so they don't clutter namespaces forever.
+func (dc *DeploymentController) sweepAbandonedReplicaSets() {
+	rsList, err := dc.rsLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list replica sets for the abandoned sweep: %v", err))
+		return
+	}
+	for _, rs := range rsList {
+		// With sharding enabled, every namespace has exactly one sweeping instance.
+		if !dc.ownsNamespace(rs.Namespace) {
+			continue
+		}
+		controllerRef := metav1.GetControllerOf(rs)
+		if controllerRef == nil || !ownerRefIsDeployment(controllerRef) {
+			continue
+		}
+		if rs.DeletionTimestamp != nil || rs.Status.Replicas != 0 || *(rs.Spec.Replicas) != 0 {
+			continue
+		}
+		if time.Since(rs.CreationTimestamp.Time) < abandonedRSMinAge {
+			continue
+		}
+		owner, err := dc.dLister.Deployments(rs.Namespace).Get(controllerRef.Name)
+		if err == nil && owner.UID == controllerRef.UID {
+			continue
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			continue
+		}
+		// The owner is gone (or has been replaced by a namesake): double check with a
+		// quorum read before deleting, the informer may just be lagging.
+		fresh, err := dc.client.AppsV1().Deployments(rs.Namespace).Get(context.TODO(), controllerRef.Name, metav1.GetOptions{})
+		if err == nil && fresh.UID == controllerRef.UID {
+			continue
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			continue
+		}
+		klog.V(2).InfoS("Deleting abandoned replica set with dead owner", "replicaSet", klog.KObj(rs), "owner", controllerRef.Name)
+		if err := dc.client.AppsV1().ReplicaSets(rs.Namespace).Delete(context.TODO(), rs.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			utilruntime.HandleError(fmt.Errorf("couldn't delete abandoned replica set %s/%s: %v", rs.Namespace, rs.Name, err))
+			continue
+		}
+		dc.eventRecorder.Eventf(rs, v1.EventTypeNormal, "AbandonedReplicaSetDeleted",
+			"Deleted replica set %q whose owning deployment %q no longer exists", rs.Name, controllerRef.Name)
+		dc.auditMutation("ReplicaSet", rs.Namespace, rs.Name, "delete", "", "AbandonedReplicaSetDeleted")
+	}
+}
+
+// workerScalePeriod is how often the dynamic worker pool is reevaluated.
+const workerScalePeriod = 30 * time.Second
+
+// SetMaxWorkers allows the controller to grow its worker pool up to the given size while
+// the queue stays deep, shrinking back to the base count once the backlog drains. It
+// must be called before Run.
+func (dc *DeploymentController) SetMaxWorkers(maxWorkers int) {
+	dc.maxWorkers = maxWorkers
+}
+
+// scaleWorkers grows and shrinks the worker pool between the base count and maxWorkers
+// based on sustained queue depth: a queue deeper than the current pool adds a worker per
+// period, an empty queue retires one extra worker per period. Retiring only signals the
+// worker to stop picking up new items — its sync context stays intact, so a shrink never
+// aborts in-flight API calls mid-rollout (the same guarantee the shutdown drain gives).
+func (dc *DeploymentController) scaleWorkers(ctx context.Context, baseWorkers int) {
+	var extraWorkers []chan struct{}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		depth := dc.queue.Len()
+		current := baseWorkers + len(extraWorkers)
+		switch {
+		case depth > current && current < dc.maxWorkers:
+			stopCh := make(chan struct{})
+			extraWorkers = append(extraWorkers, stopCh)
+			go dc.extraWorker(ctx, stopCh)
+			klog.V(2).InfoS("Grew deployment worker pool", "workers", current+1, "queueDepth", depth)
+		case depth == 0 && len(extraWorkers) > 0:
+			last := len(extraWorkers) - 1
+			close(extraWorkers[last])
+			extraWorkers = extraWorkers[:last]
+			klog.V(2).InfoS("Shrank deployment worker pool", "workers", current-1)
+		}
+	}, workerScalePeriod)
+	for _, stopCh := range extraWorkers {
+		close(stopCh)
+	}
+}
+
+// extraWorker is a dynamically added worker: it checks its stop signal only between
+// items, never cancelling a sync it already started.
+func (dc *DeploymentController) extraWorker(ctx context.Context, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if !dc.processNextWorkItem(ctx) {
+			return
+		}
+	}
+}
+
+// sampleQueueDepths exports the current depths of the controller's work queues.
+func (dc *DeploymentController) sampleQueueDepths() {
+	queueDepth.WithLabelValues("deployment").Set(float64(dc.queue.Len()))
+	queueDepth.WithLabelValues("deployment-urgent").Set(float64(dc.urgentQueue.Len()))
+	queueDepth.WithLabelValues("deployment-cleanup").Set(float64(dc.cleanupQueue.Len()))
+}
+
+// enqueueCleanup schedules asynchronous history cleanup for a deployment.
+func (dc *DeploymentController) enqueueCleanup(deployment *apps.Deployment) {
+	key, err := controller.KeyFunc(deployment)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %#v: %v", deployment, err))
+		return
+	}
+	dc.cleanupQueue.Add(key)
+}
+
+// cleanupWorker processes the history cleanup queue.
+func (dc *DeploymentController) cleanupWorker(ctx context.Context) {
+	for dc.processNextCleanupWorkItem(ctx) {
+	}
+}
+
+func (dc *DeploymentController) processNextCleanupWorkItem(ctx context.Context) bool {
+	key, quit := dc.cleanupQueue.Get()
+	if quit {
+		return false
+	}
+	defer dc.cleanupQueue.Done(key)
+
+	err := dc.syncCleanup(ctx, key.(string))
+	if err == nil {
+		dc.cleanupQueue.Forget(key)
+		return true
+	}
+	utilruntime.HandleError(fmt.Errorf("error cleaning up deployment %v: %v", key, err))
+	if dc.cleanupQueue.NumRequeues(key) < maxRetries {
+		dc.cleanupQueue.AddRateLimited(key)
+	} else {
+		dc.cleanupQueue.Forget(key)
+	}
+	return true
+}
+
+// listOwnedReplicaSets returns the replica sets owned by the deployment. When the
+// owner-UID index is available the lookup is O(owned) instead of scanning the whole
+// namespace; unlike getReplicaSetsForDeployment it performs no adoption or orphaning.
+func (dc *DeploymentController) listOwnedReplicaSets(deployment *apps.Deployment) ([]*apps.ReplicaSet, error) {
+	if dc.rsIndexer != nil {
+		objects, err := dc.rsIndexer.ByIndex(replicaSetControllerUIDIndex, string(deployment.UID))
+		if err != nil {
+			return nil, err
+		}
+		owned := make([]*apps.ReplicaSet, 0, len(objects))
+		for _, object := range objects {
+			if rs, ok := object.(*apps.ReplicaSet); ok && rs.Namespace == deployment.Namespace {
+				owned = append(owned, rs)
+			}
+		}
+		return owned, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("deployment %s/%s has invalid label selector: %v", deployment.Namespace, deployment.Name, err)
+	}
+	all, err := dc.rsLister.ReplicaSets(deployment.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	owned := make([]*apps.ReplicaSet, 0, len(all))
+	for _, rs := range all {
+		if metav1.IsControlledBy(rs, deployment) {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// syncCleanup performs history cleanup for the deployment with the given key, using only
+// lister data. Adoption/orphaning is left to the regular sync path.
+func (dc *DeploymentController) syncCleanup(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	deployment, err := dc.dLister.Deployments(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Cleanup may update status conditions; don't mutate the cache object.
+	deployment = deployment.DeepCopy()
+	owned, err := dc.listOwnedReplicaSets(deployment)
+	if err != nil {
+		return err
+	}
+	_, allOldRSs := util.FindOldReplicaSets(deployment, owned)
+	return dc.cleanupDeployment(ctx, allOldRSs, deployment)
+}
+
 // worker runs a worker thread that just dequeues items, processes them, and marks them done.
 // It enforces that the syncHandler is never invoked concurrently with the same key.
-func (dc *DeploymentController) worker() {
-	for dc.processNextWorkItem() {
+func (dc *DeploymentController) worker(ctx context.Context) {
+	for dc.processNextWorkItem(ctx) {
 	}
 }
 
-func (dc *DeploymentController) processNextWorkItem() bool {
+// urgentWorker drains the urgent queue, serving troubled deployments ahead of routine
+// resyncs during backlog storms.
+func (dc *DeploymentController) urgentWorker(ctx context.Context) {
+	for dc.processNextWorkItemFor(ctx, dc.urgentQueue) {
+	}
+}
+
+func (dc *DeploymentController) processNextWorkItem(ctx context.Context) bool {
+	return dc.processNextWorkItemFor(ctx, dc.queue)
+}
+
+func (dc *DeploymentController) processNextWorkItemFor(ctx context.Context, queue workqueue.RateLimitingInterface) bool {
 	// 큐에서 아이템을 하나 꺼낸다.
-	key, quit := dc.queue.Get()
+	key, quit := queue.Get()
 	if quit {
 		return false
 	}
 	// defer로 처리가 끝내면 해당 key를 done 한다.
-	defer dc.queue.Done(key)
+	defer queue.Done(key)
+
+	// ShutDown keeps handing out the backlog until the queue is empty; during the drain
+	// no brand-new syncs may start, the remaining work is intentionally left behind.
+	if atomic.LoadInt32(&dc.draining) != 0 {
+		return true
+	}
+
+	// Under backlog, don't let one namespace monopolize the workers: keys from a
+	// namespace that already consumed its share of the recent syncs step back briefly
+	// while other work is pending.
+	if queue.Len() > 0 && dc.deferForNamespaceFairness(key.(string)) {
+		queue.AddAfter(key, namespaceFairnessDelay)
+		return true
+	}
+
+	// Likewise bound how many deployments of one namespace run at the same time, so a
+	// single tenant's mass redeploy can't occupy every worker goroutine at once.
+	if dc.namespaceSaturated(key.(string)) {
+		queue.AddAfter(key, namespaceFairnessDelay)
+		return true
+	}
+
+	// The same key may sit in both the regular and the urgent queue; never run it
+	// concurrently with itself.
+	if !dc.startSync(key.(string)) {
+		queue.AddRateLimited(key)
+		return true
+	}
+	defer dc.finishSync(key.(string))
 
 	// 처리를 수행한다.
-	err := dc.syncHandler(key.(string))
-	dc.handleErr(err, key)
+	syncStart := time.Now()
+	err := dc.syncHandler(ctx, key.(string))
+	syncDurationSeconds.Observe(time.Since(syncStart).Seconds())
+	dc.lastSyncErrMu.Lock()
+	if err == nil {
+		delete(dc.lastSyncErrors, key.(string))
+	} else {
+		dc.lastSyncErrors[key.(string)] = err.Error()
+	}
+	dc.lastSyncErrMu.Unlock()
+	if err == nil {
+		dc.noteSuccessfulSync()
+	}
+	dc.recordNamespaceSync(key.(string))
+	dc.handleErrFor(err, key, queue)
+
+	return true
+}
+
+const (
+	// throttleWindow and throttleTripCount define when the backpressure circuit opens:
+	// at least throttleTripCount throttling errors within throttleWindow.
+	throttleWindow    = time.Minute
+	throttleTripCount = 5
+	// throttleBackoff is the lengthened requeue delay while the circuit is open.
+	throttleBackoff = 30 * time.Second
+
+	// namespaceFairnessWindow is how far back processed syncs count towards a
+	// namespace's share.
+	namespaceFairnessWindow = time.Second
+	// namespaceFairnessBurst is how many syncs a single namespace may occupy within the
+	// fairness window while other work is pending.
+	namespaceFairnessBurst = 12
+	// namespaceFairnessDelay is how long an over-budget key steps back.
+	namespaceFairnessDelay = 50 * time.Millisecond
+)
+
+// noteThrottle records an apiserver throttling error.
+func (dc *DeploymentController) noteThrottle() {
+	dc.throttleMu.Lock()
+	dc.throttleTimes = append(dc.throttleTimes, time.Now())
+	dc.throttleMu.Unlock()
+}
+
+// throttled reports whether the backpressure circuit is open.
+func (dc *DeploymentController) throttled() bool {
+	cutoff := time.Now().Add(-throttleWindow)
+	dc.throttleMu.Lock()
+	defer dc.throttleMu.Unlock()
+	kept := dc.throttleTimes[:0]
+	for _, at := range dc.throttleTimes {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	dc.throttleTimes = kept
+	return len(kept) >= throttleTripCount
+}
+
+// deferForNamespaceFairness reports whether the key's namespace has exceeded its share
+// of the recently processed syncs. Entries expire with the window, so a hot namespace is
+// throttled rather than starved even when it is the only one with pending work.
+func (dc *DeploymentController) deferForNamespaceFairness(key string) bool {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return false
+	}
+	cutoff := time.Now().Add(-namespaceFairnessWindow)
+	dc.nsFairnessMu.Lock()
+	defer dc.nsFairnessMu.Unlock()
+	kept := dc.recentSyncNamespaces[:0]
+	count := 0
+	for _, record := range dc.recentSyncNamespaces {
+		if record.at.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, record)
+		if record.namespace == namespace {
+			count++
+		}
+	}
+	dc.recentSyncNamespaces = kept
+	return count >= namespaceFairnessBurst
+}
+
+// recordNamespaceSync adds a processed sync to the fairness window.
+func (dc *DeploymentController) recordNamespaceSync(key string) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	dc.nsFairnessMu.Lock()
+	dc.recentSyncNamespaces = append(dc.recentSyncNamespaces, namespaceSyncRecord{namespace: namespace, at: time.Now()})
+	dc.nsFairnessMu.Unlock()
+}
 
+// maxConcurrentSyncsPerNamespace bounds how many deployments of the same namespace may
+// be synced concurrently.
+const maxConcurrentSyncsPerNamespace = 4
+
+// namespaceSaturated reports whether the key's namespace already has its maximum number
+// of concurrent syncs in flight.
+func (dc *DeploymentController) namespaceSaturated(key string) bool {
+	if dc.inFlight == nil {
+		return false
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return false
+	}
+	prefix := namespace + "/"
+	count := 0
+	dc.inFlightMu.Lock()
+	defer dc.inFlightMu.Unlock()
+	for inFlightKey := range dc.inFlight {
+		if strings.HasPrefix(inFlightKey, prefix) {
+			count++
+		}
+	}
+	return count >= maxConcurrentSyncsPerNamespace
+}
+
+// SyncOnce triggers one synchronous reconciliation of the named deployment, bypassing
+// the work queue, and returns its error. It honors the in-flight guard, waiting for a
+// concurrently running sync of the same key to finish first, so callers get
+// deterministic ordering — useful for CLI tooling, integration tests and debugging.
+func (dc *DeploymentController) SyncOnce(ctx context.Context, namespace, name string) error {
+	key := namespace + "/" + name
+	for !dc.startSync(key) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	defer dc.finishSync(key)
+	return dc.syncHandler(ctx, key)
+}
+
+// startSync marks a key as being synced, returning false when it already is.
+func (dc *DeploymentController) startSync(key string) bool {
+	if dc.inFlight == nil {
+		return true
+	}
+	dc.inFlightMu.Lock()
+	defer dc.inFlightMu.Unlock()
+	if dc.inFlight[key] {
+		return false
+	}
+	dc.inFlight[key] = true
 	return true
 }
 
+// finishSync clears the in-flight mark of a key.
+func (dc *DeploymentController) finishSync(key string) {
+	if dc.inFlight == nil {
+		return
+	}
+	dc.inFlightMu.Lock()
+	delete(dc.inFlight, key)
+	dc.inFlightMu.Unlock()
+}
+
 func (dc *DeploymentController) handleErr(err error, key interface{}) {
+	dc.handleErrFor(err, key, dc.queue)
+}
+
+func (dc *DeploymentController) handleErrFor(err error, key interface{}, queue workqueue.RateLimitingInterface) {
 	if err == nil || errors.HasStatusCause(err, v1.NamespaceTerminatingCause) {
-		dc.queue.Forget(key)
+		queue.Forget(key)
+		dc.forgetClassifiedRetries(key)
 		return
 	}
 
@@ -493,24 +1399,83 @@ func (dc *DeploymentController) handleErr(err error, key interface{}) {
 		klog.ErrorS(err, "Failed to split meta namespace cache key", "cacheKey", key)
 	}
 
-	if dc.queue.NumRequeues(key) < maxRetries {
+	// Sustained throttling by the apiserver opens a circuit breaker: requeue with a
+	// long flat delay (without burning the per-item retry budget) instead of hammering
+	// an already overloaded server.
+	if errors.IsTooManyRequests(err) {
+		dc.noteThrottle()
+	}
+	if dc.throttled() {
+		klog.V(2).InfoS("Backing off syncing deployment, the apiserver is throttling", "deployment", klog.KRef(ns, name), "err", err)
+		queue.AddAfter(key, throttleBackoff)
+		return
+	}
+
+	retryLimit := dc.maxSyncRetries
+	if retryLimit == 0 {
+		retryLimit = maxRetries
+	}
+	// Classified retries go through their own per-item backoffs; their counts add to
+	// the queue's so every error class still runs into the retry cap.
+	if queue.NumRequeues(key)+dc.classifiedRetries(key) < retryLimit {
 		klog.V(2).InfoS("Error syncing deployment", "deployment", klog.KRef(ns, name), "err", err)
-		dc.queue.AddRateLimited(key)
+		syncRetriesTotal.Inc()
+		// Retry according to the error class instead of one exponential ladder for
+		// everything: conflicts and transient server trouble resolve quickly and are
+		// retried with a short, growing backoff, while validation-type errors won't fix
+		// themselves and are parked with a long delay until the user intervenes.
+		switch {
+		case dc.conflictBackoff == nil || dc.parkBackoff == nil:
+			queue.AddRateLimited(key)
+		case errors.IsConflict(err) || errors.IsServerTimeout(err) || errors.IsTimeout(err) || errors.IsServiceUnavailable(err):
+			queue.AddAfter(key, dc.conflictBackoff.When(key))
+		case errors.IsInvalid(err) || errors.IsBadRequest(err) || errors.IsForbidden(err):
+			queue.AddAfter(key, dc.parkBackoff.When(key))
+		default:
+			queue.AddRateLimited(key)
+		}
 		return
 	}
 
 	utilruntime.HandleError(err)
 	klog.V(2).InfoS("Dropping deployment out of the queue", "deployment", klog.KRef(ns, name), "err", err)
-	dc.queue.Forget(key)
+	queue.Forget(key)
+	dc.forgetClassifiedRetries(key)
+}
+
+// classifiedRetries returns how often the key was retried through the class-specific
+// backoffs.
+func (dc *DeploymentController) classifiedRetries(key interface{}) int {
+	if dc.conflictBackoff == nil || dc.parkBackoff == nil {
+		return 0
+	}
+	return dc.conflictBackoff.NumRequeues(key) + dc.parkBackoff.NumRequeues(key)
+}
+
+// forgetClassifiedRetries resets the class-specific backoffs of a key.
+func (dc *DeploymentController) forgetClassifiedRetries(key interface{}) {
+	if dc.conflictBackoff == nil || dc.parkBackoff == nil {
+		return
+	}
+	dc.conflictBackoff.Forget(key)
+	dc.parkBackoff.Forget(key)
 }
 
 // getReplicaSetsForDeployment uses ControllerRefManager to reconcile
 // ControllerRef by adopting and orphaning.
 // It returns the list of ReplicaSets that this Deployment should manage.
-func (dc *DeploymentController) getReplicaSetsForDeployment(d *apps.Deployment) ([]*apps.ReplicaSet, error) {
+func (dc *DeploymentController) getReplicaSetsForDeployment(ctx context.Context, d *apps.Deployment) ([]*apps.ReplicaSet, error) {
 	// List all ReplicaSets to find those we own but that no longer match our
-	// selector. They will be orphaned by ClaimReplicaSets().
-	rsList, err := dc.rsLister.ReplicaSets(d.Namespace).List(labels.Everything())
+	// selector. They will be orphaned by ClaimReplicaSets(). With orphan adoption
+	// disabled only the owned replica sets matter, and the owner-UID index avoids the
+	// namespace-wide list entirely.
+	var rsList []*apps.ReplicaSet
+	var err error
+	if dc.rsIndexer != nil && (dc.disableOrphanAdoption || d.Annotations[util.NoOrphanAdoptionAnnotation] == "true") {
+		rsList, err = dc.listOwnedReplicaSets(d)
+	} else {
+		rsList, err = dc.rsLister.ReplicaSets(d.Namespace).List(labels.Everything())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -518,10 +1483,22 @@ func (dc *DeploymentController) getReplicaSetsForDeployment(d *apps.Deployment)
 	if err != nil {
 		return nil, fmt.Errorf("deployment %s/%s has invalid label selector: %v", d.Namespace, d.Name, err)
 	}
+	// With orphan adoption disabled (controller-wide or per deployment), only replica
+	// sets that already have a controller reference take part in claiming; orphans with
+	// overlapping selectors are left alone.
+	if dc.disableOrphanAdoption || d.Annotations[util.NoOrphanAdoptionAnnotation] == "true" {
+		owned := make([]*apps.ReplicaSet, 0, len(rsList))
+		for _, rs := range rsList {
+			if metav1.GetControllerOf(rs) != nil {
+				owned = append(owned, rs)
+			}
+		}
+		rsList = owned
+	}
 	// If any adoptions are attempted, we should first recheck for deletion with
 	// an uncached quorum read sometime after listing ReplicaSets (see #42639).
 	canAdoptFunc := controller.RecheckDeletionTimestamp(func() (metav1.Object, error) {
-		fresh, err := dc.client.AppsV1().Deployments(d.Namespace).Get(context.TODO(), d.Name, metav1.GetOptions{})
+		fresh, err := dc.client.AppsV1().Deployments(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
 		if err != nil {
 			return nil, err
 		}
@@ -530,6 +1507,22 @@ func (dc *DeploymentController) getReplicaSetsForDeployment(d *apps.Deployment)
 		}
 		return fresh, nil
 	})
+	// Summarize bulk ownership reconciliation in one event instead of leaving only the
+	// per-object patches behind.
+	adoptions, releases := 0, 0
+	for _, rs := range rsList {
+		controllerRef := metav1.GetControllerOf(rs)
+		switch {
+		case controllerRef == nil && deploymentSelector.Matches(labels.Set(rs.Labels)):
+			adoptions++
+		case controllerRef != nil && controllerRef.UID == d.UID && !deploymentSelector.Matches(labels.Set(rs.Labels)):
+			releases++
+		}
+	}
+	if adoptions+releases > 1 {
+		dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "ReconcilingOwnership",
+			"Reconciling ownership of replica sets: %d to adopt, %d to release", adoptions, releases)
+	}
 	cm := controller.NewReplicaSetControllerRefManager(dc.rsControl, d, deploymentSelector, controllerKind, canAdoptFunc)
 	return cm.ClaimReplicaSets(rsList)
 }
@@ -541,6 +1534,26 @@ func (dc *DeploymentController) getReplicaSetsForDeployment(d *apps.Deployment)
 // NOTE: The pod pointers returned by this method point the pod objects in the cache and thus
 // shouldn't be modified in any way.
 func (dc *DeploymentController) getPodMapForDeployment(d *apps.Deployment, rsList []*apps.ReplicaSet) (map[types.UID][]*v1.Pod, error) {
+	// With the owner-UID index available, look the pods up per replica set instead of
+	// listing by selector and filtering, which is expensive for broad selectors in
+	// large namespaces.
+	if dc.podIndexer != nil {
+		podMap := make(map[types.UID][]*v1.Pod, len(rsList))
+		for _, rs := range rsList {
+			podMap[rs.UID] = []*v1.Pod{}
+			objects, err := dc.podIndexer.ByIndex(podControllerUIDIndex, string(rs.UID))
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range objects {
+				if pod, ok := object.(*v1.Pod); ok {
+					podMap[rs.UID] = append(podMap[rs.UID], pod)
+				}
+			}
+		}
+		return podMap, nil
+	}
+
 	// Get all Pods that potentially belong to this Deployment.
 	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
 	if err != nil {
@@ -570,21 +1583,218 @@ func (dc *DeploymentController) getPodMapForDeployment(d *apps.Deployment, rsLis
 	return podMap, nil
 }
 
+// nodeHoldRecheckPeriod is how often a deployment held for node unavailability is
+// rechecked.
+const nodeHoldRecheckPeriod = 30 * time.Second
+
+// SetQueueRateLimiter tunes the retry behavior of the sync queues: an exponential
+// per-item backoff between baseDelay and maxDelay (keeping the standard overall rate
+// bucket), and a cap on per-item retries. Zero values keep the respective default. It
+// must be called before Run, while no events are flowing yet.
+func (dc *DeploymentController) SetQueueRateLimiter(baseDelay, maxDelay time.Duration, maxSyncRetries int32) {
+	if baseDelay > 0 && maxDelay > 0 {
+		limiter := workqueue.NewMaxOfRateLimiter(
+			workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+			&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+		)
+		dc.queue = workqueue.NewNamedRateLimitingQueue(limiter, "deployment")
+		dc.urgentQueue = workqueue.NewNamedRateLimitingQueue(limiter, "deployment-urgent")
+	}
+	if maxSyncRetries > 0 {
+		dc.maxSyncRetries = int(maxSyncRetries)
+	}
+}
+
+// SetShard restricts this controller instance to the namespaces whose FNV-1a hash maps
+// to the given shard, allowing very large clusters to spread deployments over several
+// controller instances (each typically running under its own Lease-based leader election
+// identity). All instances must agree on shardCount. It must be called before Run.
+func (dc *DeploymentController) SetShard(shardIndex, shardCount int32) {
+	dc.shardIndex = shardIndex
+	dc.shardCount = shardCount
+}
+
+// ownsNamespace reports whether this instance's shard covers the namespace.
+func (dc *DeploymentController) ownsNamespace(namespace string) bool {
+	if dc.shardCount <= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(namespace))
+	return int32(hash.Sum32()%uint32(dc.shardCount)) == dc.shardIndex
+}
+
+// SetClock injects a clock used for the controller's deadline and age computations,
+// letting tests fast-forward time deterministically. It must be called before Run.
+func (dc *DeploymentController) SetClock(c clock.Clock) {
+	dc.clock = c
+}
+
+// now returns the controller's notion of the current time: the injected clock when one
+// is set, otherwise the package-level hook kept for legacy tests.
+func (dc *DeploymentController) now() time.Time {
+	if dc.clock != nil {
+		return dc.clock.Now()
+	}
+	return nowFn()
+}
+
+// deploymentStatusFieldManager is the field manager the controller applies status with.
+const deploymentStatusFieldManager = "deployment-controller-status"
+
+// SetServerSideApplyForStatus switches deployment status writes to server-side apply
+// under a dedicated field manager, so the controller stops clobbering status fields set
+// by other actors and avoids conflict-driven retries. It must be called before Run.
+func (dc *DeploymentController) SetServerSideApplyForStatus(enabled bool) {
+	dc.applyStatusUpdates = enabled
+}
+
+// SetStatusWriteMinInterval bounds how often the controller writes status per
+// deployment. Writes arriving sooner than the interval are coalesced: the deployment is
+// requeued for the remainder and the next sync recomputes and persists fresh status.
+// It must be called before Run.
+func (dc *DeploymentController) SetStatusWriteMinInterval(interval time.Duration) {
+	dc.statusWriteMinInterval = interval
+}
+
+// writeDeploymentStatus persists the given status for the deployment, either with a
+// plain UpdateStatus or, when configured, with server-side apply. With a status write
+// interval configured, per-deployment write bursts are coalesced into a later sync.
+func (dc *DeploymentController) writeDeploymentStatus(ctx context.Context, d *apps.Deployment, newStatus apps.DeploymentStatus) error {
+	if dc.statusWriteMinInterval > 0 && dc.statusWriteTimes != nil {
+		key := d.Namespace + "/" + d.Name
+		now := time.Now()
+		dc.statusWriteMu.Lock()
+		last, seen := dc.statusWriteTimes[key]
+		if seen && now.Sub(last) < dc.statusWriteMinInterval {
+			dc.statusWriteMu.Unlock()
+			// Defer the write; the requeued sync recomputes status, so nothing is lost.
+			dc.enqueueAfter(d, dc.statusWriteMinInterval-now.Sub(last)+time.Second)
+			return nil
+		}
+		dc.statusWriteTimes[key] = now
+		dc.statusWriteMu.Unlock()
+	}
+
+	d.Status = newStatus
+	if !dc.applyStatusUpdates {
+		_, err := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
+		return err
+	}
+	applyObject := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      d.Name,
+			"namespace": d.Namespace,
+		},
+		"status": d.Status,
+	}
+	data, err := json.Marshal(applyObject)
+	if err != nil {
+		return err
+	}
+	force := true
+	_, err = dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: deploymentStatusFieldManager, Force: &force}, "status")
+	return err
+}
+
+// SetProgressRecheckIntervals clamps the requeue delay used for progress checks of
+// potentially stuck deployments: a minimum bounds API churn, a maximum bounds how long a
+// stuck rollout can go unnoticed. Zero values leave the deadline-derived delay
+// untouched. It must be called before Run.
+func (dc *DeploymentController) SetProgressRecheckIntervals(min, max time.Duration) {
+	dc.progressRecheckMinInterval = min
+	dc.progressRecheckMaxInterval = max
+}
+
+// SetDisableOrphanAdoption disables automatic adoption of orphaned replica sets
+// controller-wide. It must be called before Run.
+func (dc *DeploymentController) SetDisableOrphanAdoption(disable bool) {
+	dc.disableOrphanAdoption = disable
+}
+
+// SetRollbackWebhook makes the controller POST a JSON notification to the given URL for
+// every automatic rollback it performs, so incident tooling reacts without watching
+// events. It must be called before Run.
+func (dc *DeploymentController) SetRollbackWebhook(url string) {
+	dc.rollbackWebhookURL = url
+}
+
+// SetNodeHoldThreshold makes the controller hold rollout progression while more than the
+// given percentage of nodes is unschedulable or not ready. It must be called before Run.
+func (dc *DeploymentController) SetNodeHoldThreshold(percent int32) {
+	dc.nodeHoldThresholdPercent = percent
+}
+
+// tooManyNodesUnavailable reports whether the fraction of unschedulable or not-ready
+// nodes exceeds the configured hold threshold, along with the observed percentage.
+func (dc *DeploymentController) tooManyNodesUnavailable() (bool, int32) {
+	nodes, err := dc.nodeLister.List(labels.Everything())
+	if err != nil || len(nodes) == 0 {
+		return false, 0
+	}
+	unavailable := 0
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			unavailable++
+			continue
+		}
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == v1.NodeReady && cond.Status != v1.ConditionTrue {
+				unavailable++
+				break
+			}
+		}
+	}
+	percent := int32(unavailable * 100 / len(nodes))
+	return percent > dc.nodeHoldThresholdPercent, percent
+}
+
+// echoStatusRefreshNonce acknowledges a pending status refresh request by copying the
+// request nonce into the echo annotation. It runs after a sync has recomputed and
+// persisted status, so once the echo is visible, callers know the status is at least as
+// fresh as their request.
+func (dc *DeploymentController) echoStatusRefreshNonce(ctx context.Context, d *apps.Deployment) error {
+	nonce, ok := d.Annotations[util.StatusRefreshAnnotation]
+	if !ok || d.Annotations[util.StatusRefreshedAnnotation] == nonce {
+		return nil
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, util.StatusRefreshedAnnotation, nonce))
+	_, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // syncDeployment will sync the deployment with the given key.
 // This function is not meant to be invoked concurrently with the same key.
-func (dc *DeploymentController) syncDeployment(key string) error {
+func (dc *DeploymentController) syncDeployment(ctx context.Context, key string) error {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		klog.ErrorS(err, "Failed to split meta namespace cache key", "cacheKey", key)
 		return err
 	}
 
+	// Keys outside this instance's shard may still surface through requeues that
+	// predate a shard change; leave them to the owning instance.
+	if !dc.ownsNamespace(namespace) {
+		return nil
+	}
+
 	startTime := time.Now()
 	klog.V(4).InfoS("Started syncing deployment", "deployment", klog.KRef(namespace, name), "startTime", startTime)
 	defer func() {
 		klog.V(4).InfoS("Finished syncing deployment", "deployment", klog.KRef(namespace, name), "duration", time.Since(startTime))
 	}()
 
+	// Trace the sync phases so slow rollouts can be diagnosed without raising log
+	// verbosity cluster-wide; traces over the threshold are logged with per-phase
+	// timings.
+	trace := utiltrace.New("deployment-sync",
+		utiltrace.Field{Key: "namespace", Value: namespace},
+		utiltrace.Field{Key: "deployment", Value: name})
+	defer trace.LogIfLong(500 * time.Millisecond)
+
 	// string으로 받은 키를 이용해서 deployment 객체의 정보를 얻어온다.
 	deployment, err := dc.dLister.Deployments(namespace).Get(name)
 	// 이시점에서 이미 Get할 게 있다는건가? 내가 생각한 거로는 아직 생성되지 않았으니까 없어야 하는데...저 Get이 어디서 하는거지? etcd의 정보를 GET한다는 건가?
@@ -598,48 +1808,66 @@ func (dc *DeploymentController) syncDeployment(key string) error {
 		return err
 	}
 
-	// Deep-copy otherwise we are mutating our cache.
-	// TODO: Deep-copy only when needed.
-	d := deployment.DeepCopy()
-
 	everything := metav1.LabelSelector{}
-	if reflect.DeepEqual(d.Spec.Selector, &everything) {
-		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "SelectingAll", "This deployment is selecting all pods. A non-empty selector is required.")
-		if d.Status.ObservedGeneration < d.Generation {
+	if reflect.DeepEqual(deployment.Spec.Selector, &everything) {
+		dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, "SelectingAll", "This deployment is selecting all pods. A non-empty selector is required.")
+		if deployment.Status.ObservedGeneration < deployment.Generation {
+			d := deployment.DeepCopy()
 			d.Status.ObservedGeneration = d.Generation
-			dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{})
+			dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
 		}
 		return nil
 	}
 
 	// List ReplicaSets owned by this Deployment, while reconciling ControllerRef
-	// through adoption/orphaning.
-	rsList, err := dc.getReplicaSetsForDeployment(d)
+	// through adoption/orphaning. This only reads the deployment, so the cache object
+	// is still fine here.
+	rsList, err := dc.getReplicaSetsForDeployment(ctx, deployment)
 	fmt.Println("rsList-----------: ", rsList)
 
 	if err != nil {
 		return err
 	}
+	trace.Step("Replica sets claimed")
 	// List all Pods owned by this Deployment, grouped by their ReplicaSet.
 	// Current uses of the podMap are:
 	//
 	// * check if a Pod is labeled correctly with the pod-template-hash label.
 	// * check that no old Pods are running in the middle of Recreate Deployments.
-	podMap, err := dc.getPodMapForDeployment(d, rsList)
+	podMap, err := dc.getPodMapForDeployment(deployment, rsList)
 	fmt.Println("podMap-----------: ", podMap)
 
 	if err != nil {
 		return err
 	}
+	trace.Step("Pod map built")
+
+	// Everything below may mutate the deployment (conditions, annotations, spec on
+	// pause/rollback); copy only now so read-only resyncs that bailed out above didn't
+	// pay for a deep copy.
+	err = dc.ReconcileDeployment(ctx, deployment.DeepCopy(), rsList, podMap)
+	trace.Step("Deployment reconciled")
+	return err
+}
+
+// ReconcileDeployment performs one reconciliation of the given deployment against the
+// provided replica sets and pod map: pause/hold handling, rollback, scaling and the
+// strategy-specific rollout, exactly as the controller's own sync loop does. It is
+// exported so out-of-tree controllers can reuse the rollout machinery without the
+// informer and queue plumbing; d must be a private copy the caller may let this method
+// mutate, rsList should only contain replica sets owned by d, and podMap may be nil for
+// rolling updates.
+func (dc *DeploymentController) ReconcileDeployment(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet, podMap map[types.UID][]*v1.Pod) error {
+	var err error
 
 	if d.DeletionTimestamp != nil {
-		return dc.syncStatusOnly(d, rsList)
+		return dc.syncStatusOnly(ctx, d, rsList)
 	}
 
 	// Update deployment conditions with an Unknown condition when pausing/resuming
 	// a deployment. In this way, we can be sure that we won't timeout when a user
 	// resumes a Deployment with a set progressDeadlineSeconds.
-	if err = dc.checkPausedConditions(d); err != nil {
+	if err = dc.checkPausedConditions(ctx, d); err != nil {
 		return err
 	}
 
@@ -647,17 +1875,67 @@ func (dc *DeploymentController) syncDeployment(key string) error {
 
 	if d.Spec.Paused {
 
-		return dc.sync(d, rsList)
+		// A rollback with a verification bake keeps the deployment paused until the
+		// restored revision proved itself.
+		handled, err := dc.verifyRollbackBake(ctx, d)
+		if err != nil || handled {
+			return err
+		}
+
+		// A paused deployment with a bake duration resumes on its own once it has
+		// stayed healthy for long enough.
+		promoted, err := dc.autoPromoteIfBaked(ctx, d)
+		if err != nil || promoted {
+			// The promotion itself requeues the deployment through the update event.
+			return err
+		}
+		if err = dc.sync(ctx, d, rsList); err != nil {
+			return err
+		}
+		return dc.echoStatusRefreshNonce(ctx, d)
+	}
+
+	// Hold rollout progression while a configured fraction of the cluster's nodes is
+	// unschedulable or not ready, surfacing the hold as a condition, and recheck soon.
+	if dc.nodeLister != nil && dc.nodeHoldThresholdPercent > 0 {
+		if held, unavailablePercent := dc.tooManyNodesUnavailable(); held {
+			condition := util.NewDeploymentCondition(util.DeploymentRolloutHeld, v1.ConditionTrue, util.TooManyNodesUnavailableReason,
+				fmt.Sprintf("Rollout is held because %d%% of nodes are unschedulable or not ready", unavailablePercent))
+			util.SetDeploymentCondition(&d.Status, *condition)
+			dc.enqueueAfter(d, nodeHoldRecheckPeriod)
+			return dc.sync(ctx, d, rsList)
+		}
+		util.RemoveDeploymentCondition(&d.Status, util.DeploymentRolloutHeld)
+	}
+
+	// Inside a declared freeze window the deployment behaves as if it were paused;
+	// requeue it for when the window ends so the rollout resumes promptly.
+	if frozen, until := util.InFreezeWindow(d, dc.now()); frozen {
+		klog.V(4).InfoS("Deployment is inside a freeze window", "deployment", klog.KObj(d), "until", until)
+		condition := util.NewDeploymentCondition(util.DeploymentRolloutHeld, v1.ConditionTrue, util.HeldByFreezeWindowReason,
+			fmt.Sprintf("Rollout is held by a freeze window until %s", until.Format(time.RFC3339)))
+		util.SetDeploymentCondition(&d.Status, *condition)
+		dc.enqueueAfter(d, until.Sub(dc.now())+time.Second)
+		if err = dc.sync(ctx, d, rsList); err != nil {
+			return err
+		}
+		return dc.echoStatusRefreshNonce(ctx, d)
+	}
+
+	// A deployment pinned to a revision is continuously reverted when its template
+	// drifts; the reversion is filed as a rollback request handled below on requeue.
+	if err := dc.enforcePinnedRevision(ctx, d, rsList); err != nil {
+		return err
 	}
 
 	// rollback is not re-entrant in case the underlying replica sets are updated with a new
 	// revision so we should ensure that we won't proceed to update replica sets until we
 	// make sure that the deployment has cleaned up its rollback spec in subsequent enqueues.
 	if getRollbackTo(d) != nil {
-		return dc.rollback(d, rsList)
+		return dc.rollback(ctx, d, rsList)
 	}
 
-	scalingEvent, err := dc.isScalingEvent(d, rsList)
+	scalingEvent, err := dc.isScalingEvent(ctx, d, rsList)
 
 	fmt.Println("scalingEvent-----------: ", scalingEvent)
 
@@ -665,18 +1943,34 @@ func (dc *DeploymentController) syncDeployment(key string) error {
 		return err
 	}
 	if scalingEvent {
-
-		return dc.sync(d, rsList)
+		// A scale-subresource write (e.g. by the horizontal pod autoscaler) can land in
+		// the same sync window as a template update. Handle the combination
+		// deterministically: apply the scale first, then requeue so the rollout starts in
+		// a follow-up sync against the already scaled replica sets. A missing new replica
+		// set is the sign that a template change is pending as well.
+		if err = dc.sync(ctx, d, rsList); err != nil {
+			return err
+		}
+		if util.FindNewReplicaSet(d, rsList) == nil {
+			dc.enqueueDeployment(d)
+		}
+		return dc.echoStatusRefreshNonce(ctx, d)
 	}
 
 	fmt.Println("d.Spec.Strategy.Type-----------: ", d.Spec.Strategy.Type)
 
 	switch d.Spec.Strategy.Type {
 	case apps.RecreateDeploymentStrategyType:
-		return dc.rolloutRecreate(d, rsList, podMap)
+		if err = dc.rolloutRecreate(ctx, d, rsList, podMap); err != nil {
+			return err
+		}
+		return dc.echoStatusRefreshNonce(ctx, d)
 	case apps.RollingUpdateDeploymentStrategyType:
 		// etcd 할당요청 상태로 업데이트하러 go
-		return dc.rolloutRolling(d, rsList)
+		if err = dc.rolloutRolling(ctx, d, rsList); err != nil {
+			return err
+		}
+		return dc.echoStatusRefreshNonce(ctx, d)
 	}
 	return fmt.Errorf("unexpected deployment strategy type: %s", d.Spec.Strategy.Type)
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package deployment
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"testing"
@@ -24,9 +26,11 @@ import (
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/informers"
@@ -172,6 +176,10 @@ func (f *fixture) expectCreateRSAction(rs *apps.ReplicaSet) {
 	f.actions = append(f.actions, core.NewCreateAction(schema.GroupVersionResource{Resource: "replicasets"}, rs.Namespace, rs))
 }
 
+func (f *fixture) expectPatchDeploymentAction(d *apps.Deployment) {
+	f.actions = append(f.actions, core.NewPatchAction(schema.GroupVersionResource{Resource: "deployments"}, d.Namespace, d.Name, types.MergePatchType, nil))
+}
+
 func newFixture(t testing.TB) *fixture {
 	f := &fixture{}
 	f.t = t
@@ -182,7 +190,7 @@ func newFixture(t testing.TB) *fixture {
 func (f *fixture) newController() (*DeploymentController, informers.SharedInformerFactory, error) {
 	f.client = fake.NewSimpleClientset(f.objects...)
 	informers := informers.NewSharedInformerFactory(f.client, controller.NoResyncPeriodFunc())
-	c, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), f.client)
+	c, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), informers.Policy().V1().PodDisruptionBudgets(), informers.Core().V1().Nodes(), f.client)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -190,6 +198,8 @@ func (f *fixture) newController() (*DeploymentController, informers.SharedInform
 	c.dListerSynced = alwaysReady
 	c.rsListerSynced = alwaysReady
 	c.podListerSynced = alwaysReady
+	c.pdbListerSynced = alwaysReady
+	c.nodeListerSynced = alwaysReady
 	for _, d := range f.dLister {
 		informers.Apps().V1().Deployments().Informer().GetIndexer().Add(d)
 	}
@@ -221,7 +231,7 @@ func (f *fixture) run_(deploymentName string, startInformers bool, expectError b
 		informers.Start(stopCh)
 	}
 
-	err = c.syncDeployment(deploymentName)
+	err = c.syncDeployment(context.TODO(), deploymentName)
 	if !expectError && err != nil {
 		f.t.Errorf("error syncing deployment: %v", err)
 	} else if expectError && err == nil {
@@ -254,9 +264,13 @@ func filterInformerActions(actions []core.Action) []core.Action {
 			(action.Matches("list", "pods") ||
 				action.Matches("list", "deployments") ||
 				action.Matches("list", "replicasets") ||
+				action.Matches("list", "poddisruptionbudgets") ||
+				action.Matches("list", "nodes") ||
 				action.Matches("watch", "pods") ||
 				action.Matches("watch", "deployments") ||
-				action.Matches("watch", "replicasets")) {
+				action.Matches("watch", "replicasets") ||
+				action.Matches("watch", "poddisruptionbudgets") ||
+				action.Matches("watch", "nodes")) {
 			continue
 		}
 		ret = append(ret, action)
@@ -275,7 +289,11 @@ func TestSyncDeploymentCreatesReplicaSet(t *testing.T) {
 	rs := newReplicaSet(d, "deploymentrs-4186632231", 1)
 
 	f.expectCreateRSAction(rs)
+	// Stamping the rollout start time.
+	f.expectPatchDeploymentAction(d)
 	f.expectUpdateDeploymentStatusAction(d)
+	// Publishing the current revision hash.
+	f.expectPatchDeploymentAction(d)
 	f.expectUpdateDeploymentStatusAction(d)
 
 	f.run(testutil.GetKey(d, t))
@@ -529,7 +547,7 @@ func TestGetReplicaSetsForDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 
-	rsList, err := c.getReplicaSetsForDeployment(d1)
+	rsList, err := c.getReplicaSetsForDeployment(context.TODO(), d1)
 	if err != nil {
 		t.Fatalf("getReplicaSetsForDeployment() error: %v", err)
 	}
@@ -541,7 +559,7 @@ func TestGetReplicaSetsForDeployment(t *testing.T) {
 		t.Errorf("getReplicaSetsForDeployment() = %v, want [%v]", rsNames, rs1.Name)
 	}
 
-	rsList, err = c.getReplicaSetsForDeployment(d2)
+	rsList, err = c.getReplicaSetsForDeployment(context.TODO(), d2)
 	if err != nil {
 		t.Fatalf("getReplicaSetsForDeployment() error: %v", err)
 	}
@@ -579,7 +597,7 @@ func TestGetReplicaSetsForDeploymentAdoptRelease(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 
-	rsList, err := c.getReplicaSetsForDeployment(d)
+	rsList, err := c.getReplicaSetsForDeployment(context.TODO(), d)
 	if err != nil {
 		t.Fatalf("getReplicaSetsForDeployment() error: %v", err)
 	}
@@ -1009,6 +1027,26 @@ func bumpResourceVersion(obj metav1.Object) {
 }
 
 // generatePodFromRS creates a pod, with the input ReplicaSet's selector and its template
+// replicasFromRSPatchAction extracts spec.replicas from a replica set scale patch action.
+func replicasFromRSPatchAction(t testing.TB, action core.Action) (int32, bool) {
+	patchAction, ok := action.(core.PatchAction)
+	if !ok || patchAction.GetResource().Resource != "replicasets" {
+		return 0, false
+	}
+	var patch struct {
+		Spec struct {
+			Replicas *int32 `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(patchAction.GetPatch(), &patch); err != nil {
+		t.Fatalf("couldn't decode replica set patch %q: %v", patchAction.GetPatch(), err)
+	}
+	if patch.Spec.Replicas == nil {
+		return 0, false
+	}
+	return *patch.Spec.Replicas, true
+}
+
 func generatePodFromRS(rs *apps.ReplicaSet) *v1.Pod {
 	trueVar := true
 	return &v1.Pod{
@@ -1023,3 +1061,98 @@ func generatePodFromRS(rs *apps.ReplicaSet) *v1.Pod {
 		Spec: rs.Spec.Template.Spec,
 	}
 }
+
+// TestScaleAppliedBeforeRolloutOnCombinedUpdate ensures that when a scaling event and a
+// template update land in the same sync window, the sync applies the scale and requeues the
+// deployment so the rollout starts in a follow-up sync.
+func TestScaleAppliedBeforeRolloutOnCombinedUpdate(t *testing.T) {
+	f := newFixture(t)
+
+	d := newDeployment("foo", 3, nil, nil, nil, map[string]string{"foo": "bar"})
+	d.Annotations[util.RevisionAnnotation] = "1"
+	rs := newReplicaSet(d, "foo-1", 2)
+	rs.Annotations = map[string]string{
+		util.RevisionAnnotation:        "1",
+		util.DesiredReplicasAnnotation: "2",
+		util.MaxReplicasAnnotation:     "2",
+	}
+	// The template changed as well, so there is no replica set matching the
+	// deployment's current template.
+	rs.Spec.Template = *d.Spec.Template.DeepCopy()
+	rs.Spec.Template.Spec.Containers[0].Image = "foo/bar:v2"
+
+	f.dLister = append(f.dLister, d)
+	f.rsLister = append(f.rsLister, rs)
+	f.objects = append(f.objects, d, rs)
+
+	c, informers, err := f.newController()
+	if err != nil {
+		t.Fatalf("error creating Deployment controller: %v", err)
+	}
+	enqueued := false
+	c.enqueueDeployment = func(d *apps.Deployment) {
+		if d.Name == "foo" {
+			enqueued = true
+		}
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informers.Start(stopCh)
+
+	if err := c.syncDeployment(context.TODO(), testutil.GetKey(d, t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !enqueued {
+		t.Errorf("expected the deployment to be requeued for the rollout after scaling")
+	}
+
+	scaled := false
+	for _, action := range filterInformerActions(f.client.Actions()) {
+		if replicas, ok := replicasFromRSPatchAction(t, action); ok && replicas == 3 {
+			scaled = true
+		}
+	}
+	if !scaled {
+		t.Errorf("expected the existing replica set to be scaled to the new size before the rollout")
+	}
+}
+
+// TestClearRollbackPersistsBlockedCondition ensures the RollbackBlocked condition is
+// written from the object the rollback-clearing update returned: against an apiserver
+// that bumps the resourceVersion on update, a status write from the pre-update copy
+// would conflict and the condition would never be recorded.
+func TestClearRollbackPersistsBlockedCondition(t *testing.T) {
+	d := newDeployment("foo", 1, nil, nil, nil, map[string]string{"foo": "bar"})
+	d.ResourceVersion = "1"
+	setRollbackTo(d, &extensions.RollbackConfig{Revision: 2})
+
+	client := fake.NewSimpleClientset(d)
+	client.PrependReactor("update", "deployments", func(action core.Action) (bool, runtime.Object, error) {
+		updateAction := action.(core.UpdateAction)
+		obj := updateAction.GetObject().(*apps.Deployment).DeepCopy()
+		if updateAction.GetSubresource() == "" {
+			// The spec update bumps the resourceVersion, like a real apiserver.
+			obj.ResourceVersion = "2"
+			return true, obj, nil
+		}
+		if obj.ResourceVersion != "2" {
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, obj.Name,
+				fmt.Errorf("object has been modified"))
+		}
+		return true, obj, nil
+	})
+
+	dc := &DeploymentController{
+		client:        client,
+		eventRecorder: &record.FakeRecorder{},
+	}
+	if err := dc.clearRollbackWithBlockedCondition(context.TODO(), d, util.RollbackBlockedReason, "revision 2 is marked bad"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cond := util.GetDeploymentCondition(d.Status, util.DeploymentRollbackBlocked); cond == nil {
+		t.Errorf("expected the RollbackBlocked condition to be recorded")
+	}
+	if d.ResourceVersion != "2" {
+		t.Errorf("expected the sync to continue with the updated object, got resourceVersion %q", d.ResourceVersion)
+	}
+}
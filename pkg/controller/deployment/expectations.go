@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// rsMutationExpectationTTL bounds how long a recorded self-mutation suppresses the echo
+// event. Expired expectations are ignored so a lost watch event can't suppress a real
+// one later.
+const rsMutationExpectationTTL = 2 * time.Second
+
+// rsMutationExpectations suppresses the informer echo of the controller's own replica
+// set writes, in the spirit of ControllerExpectations in the replica set controller:
+// every recorded mutation grants exactly one skip for the next observed event of that
+// replica set, so the deployment isn't re-synced for changes it just made itself.
+type rsMutationExpectations struct {
+	mu      sync.Mutex
+	pending map[types.UID][]time.Time
+}
+
+func newRSMutationExpectations() *rsMutationExpectations {
+	return &rsMutationExpectations{pending: map[types.UID][]time.Time{}}
+}
+
+// expect records one self-caused mutation of the replica set.
+func (e *rsMutationExpectations) expect(uid types.UID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending[uid] = append(e.pending[uid], time.Now().Add(rsMutationExpectationTTL))
+}
+
+// observe consumes one pending expectation for the replica set, returning true when the
+// observed event was caused by the controller itself and can be skipped.
+func (e *rsMutationExpectations) observe(uid types.UID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	deadlines := e.pending[uid]
+	now := time.Now()
+	// Drop expired expectations first.
+	kept := deadlines[:0]
+	for _, deadline := range deadlines {
+		if deadline.After(now) {
+			kept = append(kept, deadline)
+		}
+	}
+	if len(kept) == 0 {
+		delete(e.pending, uid)
+		return false
+	}
+	kept = kept[1:]
+	if len(kept) == 0 {
+		delete(e.pending, uid)
+	} else {
+		e.pending[uid] = kept
+	}
+	return true
+}
+
+// forget drops all pending expectations of the replica set.
+func (e *rsMutationExpectations) forget(uid types.UID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.pending, uid)
+}
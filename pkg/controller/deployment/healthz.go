@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
+)
+
+// controllerHealth is the payload served by the controller's health handler.
+type controllerHealth struct {
+	// Informers reports the sync state of each informer the controller depends on.
+	Informers map[string]bool `json:"informers"`
+	// LastSuccessfulSync is when a deployment sync last finished without error.
+	LastSuccessfulSync *metav1.Time `json:"lastSuccessfulSync,omitempty"`
+	// QueueDepths are the current depths of the controller's work queues.
+	QueueDepths map[string]int `json:"queueDepths"`
+	// Healthy summarizes the above: all informers synced and, while work is pending,
+	// syncs finishing recently.
+	Healthy bool `json:"healthy"`
+}
+
+// staleSyncThreshold is how long the controller may go without a successful sync while
+// work is pending before the health handler reports it as wedged.
+const staleSyncThreshold = 5 * time.Minute
+
+// noteSuccessfulSync records that a sync finished without error, for health reporting.
+func (dc *DeploymentController) noteSuccessfulSync() {
+	dc.lastSyncMu.Lock()
+	dc.lastSuccessfulSync = time.Now()
+	dc.lastSyncMu.Unlock()
+}
+
+// deploymentDebugDump is the payload served by the per-deployment debug endpoint: the
+// controller's current view of one deployment.
+type deploymentDebugDump struct {
+	Namespace          string            `json:"namespace"`
+	Name               string            `json:"name"`
+	Generation         int64             `json:"generation"`
+	ObservedGeneration int64             `json:"observedGeneration"`
+	Paused             bool              `json:"paused"`
+	Replicas           int32             `json:"replicas"`
+	NewReplicaSet      string            `json:"newReplicaSet,omitempty"`
+	OldReplicaSets     map[string]int32  `json:"oldReplicaSets,omitempty"`
+	SyncInFlight       bool              `json:"syncInFlight"`
+	LastSyncError      string            `json:"lastSyncError,omitempty"`
+	Annotations        map[string]string `json:"annotations,omitempty"`
+}
+
+// DebugHandler returns an HTTP handler with the controller's health at / and a
+// per-deployment dump at /deployment?namespace=...&name=..., answering "why isn't my
+// rollout progressing" without raising log verbosity cluster-wide.
+func (dc *DeploymentController) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", dc.HealthHandler())
+	mux.HandleFunc("/deployment", func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		name := r.URL.Query().Get("name")
+		if namespace == "" || name == "" {
+			http.Error(w, "namespace and name query parameters are required", http.StatusBadRequest)
+			return
+		}
+		deployment, err := dc.dLister.Deployments(namespace).Get(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		dump := deploymentDebugDump{
+			Namespace:          namespace,
+			Name:               name,
+			Generation:         deployment.Generation,
+			ObservedGeneration: deployment.Status.ObservedGeneration,
+			Paused:             deployment.Spec.Paused,
+			Replicas:           *(deployment.Spec.Replicas),
+			Annotations:        deployment.Annotations,
+		}
+		if owned, err := dc.listOwnedReplicaSets(deployment); err == nil {
+			newRS := deploymentutil.FindNewReplicaSet(deployment, owned)
+			if newRS != nil {
+				dump.NewReplicaSet = newRS.Name
+			}
+			_, oldRSs := deploymentutil.FindOldReplicaSets(deployment, owned)
+			dump.OldReplicaSets = map[string]int32{}
+			for _, rs := range oldRSs {
+				dump.OldReplicaSets[rs.Name] = *(rs.Spec.Replicas)
+			}
+		}
+		key := namespace + "/" + name
+		dc.inFlightMu.Lock()
+		dump.SyncInFlight = dc.inFlight[key]
+		dc.inFlightMu.Unlock()
+		dc.lastSyncErrMu.Lock()
+		dump.LastSyncError = dc.lastSyncErrors[key]
+		dc.lastSyncErrMu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(&dump); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+// HealthHandler returns an HTTP handler reporting the controller's informer sync state,
+// queue depths and the time of the last successful sync, so a wedged deployment
+// controller is detectable specifically rather than only through symptom metrics.
+func (dc *DeploymentController) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := controllerHealth{
+			Informers: map[string]bool{
+				"deployments":          dc.dListerSynced(),
+				"replicasets":          dc.rsListerSynced(),
+				"pods":                 dc.podListerSynced(),
+				"poddisruptionbudgets": dc.pdbListerSynced(),
+				"nodes":                dc.nodeListerSynced(),
+			},
+			QueueDepths: map[string]int{
+				"deployment":         dc.queue.Len(),
+				"deployment-urgent":  dc.urgentQueue.Len(),
+				"deployment-cleanup": dc.cleanupQueue.Len(),
+			},
+		}
+
+		dc.lastSyncMu.Lock()
+		lastSync := dc.lastSuccessfulSync
+		dc.lastSyncMu.Unlock()
+		if !lastSync.IsZero() {
+			t := metav1.NewTime(lastSync)
+			health.LastSuccessfulSync = &t
+		}
+
+		health.Healthy = true
+		for _, synced := range health.Informers {
+			if !synced {
+				health.Healthy = false
+			}
+		}
+		pending := health.QueueDepths["deployment"] + health.QueueDepths["deployment-urgent"]
+		if pending > 0 && !lastSync.IsZero() && time.Since(lastSync) > staleSyncThreshold {
+			health.Healthy = false
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !health.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(&health); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
+)
+
+// controllerRevisionsEnabled reports whether the deployment opted into recording its
+// revision history as ControllerRevision objects (like StatefulSet/DaemonSet), so
+// history survives aggressive replica set cleanup.
+func controllerRevisionsEnabled(d *apps.Deployment) bool {
+	return d.Annotations[deploymentutil.ControllerRevisionsAnnotation] == "true"
+}
+
+// controllerRevisionName returns the name of the ControllerRevision recording the given
+// revision of the deployment.
+func controllerRevisionName(d *apps.Deployment, revision string) string {
+	return d.Name + "-revision-" + revision
+}
+
+// saveControllerRevision records the deployment's current pod template as a
+// ControllerRevision named after the revision. Creation is idempotent: an existing
+// revision object is left untouched.
+func (dc *DeploymentController) saveControllerRevision(ctx context.Context, d *apps.Deployment, revision string) error {
+	if !controllerRevisionsEnabled(d) {
+		return nil
+	}
+	revisionNumber, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(&d.Spec.Template)
+	if err != nil {
+		return err
+	}
+	cr := &apps.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controllerRevisionName(d, revision),
+			Namespace:       d.Namespace,
+			Labels:          d.Spec.Selector.MatchLabels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(d, controllerKind)},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revisionNumber,
+	}
+	_, err = dc.client.AppsV1().ControllerRevisions(d.Namespace).Create(ctx, cr, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err == nil {
+		dc.auditMutation("ControllerRevision", cr.Namespace, cr.Name, "create", fmt.Sprintf("revision %d", revisionNumber), "SaveRevisionHistory")
+	}
+	return err
+}
+
+// rollbackToControllerRevision restores the deployment's pod template from the
+// ControllerRevision recording the requested revision. It is the fallback used when no
+// retained replica set carries the revision anymore, and returns true when it handled
+// the rollback request.
+func (dc *DeploymentController) rollbackToControllerRevision(ctx context.Context, d *apps.Deployment, revision int64) (bool, error) {
+	if !controllerRevisionsEnabled(d) {
+		return false, nil
+	}
+	name := controllerRevisionName(d, strconv.FormatInt(revision, 10))
+	cr, err := dc.client.AppsV1().ControllerRevisions(d.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	template := v1.PodTemplateSpec{}
+	if err := json.Unmarshal(cr.Data.Raw, &template); err != nil {
+		klog.Warningf("Couldn't decode ControllerRevision %q of deployment %q: %v", name, d.Name, err)
+		return false, nil
+	}
+	if deploymentutil.EqualIgnoreHash(&d.Spec.Template, &template) {
+		dc.emitRollbackWarningEvent(d, deploymentutil.RollbackTemplateUnchanged,
+			fmt.Sprintf("The rollback revision contains the same template as current deployment %q", d.Name))
+		return true, dc.updateDeploymentAndClearRollbackTo(ctx, d)
+	}
+	deploymentutil.SetFromReplicaSetTemplate(d, template)
+	if err := dc.updateDeploymentAndClearRollbackTo(ctx, d); err != nil {
+		return true, err
+	}
+	dc.emitRollbackNormalEvent(d, fmt.Sprintf("Rolled back deployment %q to revision %d from its ControllerRevision history", d.Name, revision))
+	return true, nil
+}
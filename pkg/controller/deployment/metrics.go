@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// deploymentControllerSubsystem is the name prefix of the controller's own metrics.
+const deploymentControllerSubsystem = "deployment_controller"
+
+var (
+	// dampenedEnqueuesTotal counts event-driven enqueues that were delayed because the
+	// deployment's key was already enqueued by an owned-object event within the dampening
+	// period.
+	dampenedEnqueuesTotal = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "dampened_enqueues_total",
+		Help:           "Number of pod or replica set driven enqueues delayed by per-deployment dampening.",
+		StabilityLevel: metrics.ALPHA,
+	})
+
+	// rollbacksTotal counts rollbacks the controller performed, by namespace and by what
+	// triggered them (user, CrashLoop, AnalysisFailed, RevisionPinned).
+	rollbacksTotal = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "rollbacks_total",
+		Help:           "Number of deployment rollbacks performed, by namespace and trigger.",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"namespace", "trigger"})
+
+	// progressDeadlineExceededTotal counts deployments newly entering the
+	// ProgressDeadlineExceeded state, by namespace.
+	progressDeadlineExceededTotal = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "progress_deadline_exceeded_total",
+		Help:           "Number of deployments that exceeded their progress deadline, by namespace.",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"namespace"})
+
+	// syncDurationSeconds observes how long individual deployment syncs take.
+	syncDurationSeconds = metrics.NewHistogram(&metrics.HistogramOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "sync_duration_seconds",
+		Help:           "Duration of deployment sync operations.",
+		Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+		StabilityLevel: metrics.ALPHA,
+	})
+
+	// queueDepth samples the depth of the controller's work queues.
+	queueDepth = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "queue_depth",
+		Help:           "Current depth of the deployment controller work queues.",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"queue"})
+
+	// syncRetriesTotal counts failed syncs that were requeued for retry.
+	syncRetriesTotal = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      deploymentControllerSubsystem,
+		Name:           "sync_retries_total",
+		Help:           "Number of deployment syncs that failed and were requeued.",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+var registerMetricsOnce sync.Once
+
+// registerMetrics registers the deployment controller metrics with the legacy registry.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(dampenedEnqueuesTotal)
+		legacyregistry.MustRegister(rollbacksTotal)
+		legacyregistry.MustRegister(progressDeadlineExceededTotal)
+		legacyregistry.MustRegister(syncDurationSeconds)
+		legacyregistry.MustRegister(queueDepth)
+		legacyregistry.MustRegister(syncRetriesTotal)
+	})
+}
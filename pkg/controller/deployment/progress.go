@@ -18,15 +18,21 @@ package deployment
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 	"k8s.io/kubernetes/pkg/controller/deployment/util"
 )
 
@@ -34,7 +40,7 @@ import (
 // cases this helper will run that cannot be prevented from the scaling detection,
 // for example a resync of the deployment after it was scaled up. In those cases,
 // we shouldn't try to estimate any progress.
-func (dc *DeploymentController) syncRolloutStatus(allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, d *apps.Deployment) error {
+func (dc *DeploymentController) syncRolloutStatus(ctx context.Context, allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, d *apps.Deployment) error {
 	newStatus := calculateStatus(allRSs, newRS, d)
 	fmt.Println("newStatus-----------: ", newStatus)
 
@@ -90,10 +96,19 @@ func (dc *DeploymentController) syncRolloutStatus(allRSs []*apps.ReplicaSet, new
 		case util.DeploymentTimedOut(d, &newStatus):
 			// Update the deployment with a timeout condition. If the condition already exists,
 			// we ignore this update.
+			if currentCond == nil || currentCond.Reason != util.TimedOutReason {
+				// Only count the transition into the timed-out state, not resyncs of it.
+				progressDeadlineExceededTotal.WithLabelValues(d.Namespace).Inc()
+			}
 			msg := fmt.Sprintf("Deployment %q has timed out progressing.", d.Name)
 			if newRS != nil {
 				msg = fmt.Sprintf("ReplicaSet %q has timed out progressing.", newRS.Name)
 			}
+			// Tell the user why the pods aren't coming up instead of only that they
+			// didn't.
+			if summary := dc.podFailureSummary(d, newRS); summary != "" {
+				msg = fmt.Sprintf("%s Observed pod issues: %s.", msg, summary)
+			}
 			condition := util.NewDeploymentCondition(apps.DeploymentProgressing, v1.ConditionFalse, util.TimedOutReason, msg)
 			util.SetDeploymentCondition(&newStatus, *condition)
 		}
@@ -108,19 +123,545 @@ func (dc *DeploymentController) syncRolloutStatus(allRSs []*apps.ReplicaSet, new
 		util.RemoveDeploymentCondition(&newStatus, apps.DeploymentReplicaFailure)
 	}
 
-	// Do not update if there is nothing new to add.
-	if reflect.DeepEqual(d.Status, newStatus) {
+	// Surface pod creation blocked by quota or limits as its own condition, with the
+	// quota message, instead of letting the rollout stall towards the deadline silently.
+	if blockedCondition := replicaSetCreateBlockedCondition(allRSs); blockedCondition != nil {
+		util.SetDeploymentCondition(&newStatus, *blockedCondition)
+	} else {
+		util.RemoveDeploymentCondition(&newStatus, util.DeploymentReplicaSetCreateBlocked)
+	}
+
+	// Likewise surface image pull failures of the new revision early, naming the image,
+	// instead of waiting for the generic deadline timeout.
+	if pullCondition := dc.imagePullBlockedCondition(d, newRS); pullCondition != nil {
+		util.SetDeploymentCondition(&newStatus, *pullCondition)
+	} else {
+		util.RemoveDeploymentCondition(&newStatus, util.DeploymentImagePullBlocked)
+	}
+
+	// Keep the published completion estimate, progress percentage and terminating
+	// replica count current while the rollout is in flight.
+	dc.publishRolloutETA(ctx, d, newRS, &newStatus)
+	dc.publishProgressPercent(ctx, d, newRS, &newStatus)
+	dc.publishTerminatingReplicas(ctx, d)
+	dc.publishRevisionBreakdown(ctx, d, allRSs)
+	dc.publishRevisionHashes(ctx, d, newRS, allRSs)
+	dc.publishStrategyStatus(ctx, d, newRS, &newStatus)
+
+	// Do not update if there is nothing new to add. The semantic comparison treats nil
+	// and empty condition lists as equal, so resyncs of steady-state deployments don't
+	// generate writes that differ only in such representation details.
+	if apiequality.Semantic.DeepEqual(d.Status, newStatus) {
 		// Requeue the deployment if required.
 		dc.requeueStuckDeployment(d, newStatus)
 		return nil
 	}
 
-	newDeployment := d
-	newDeployment.Status = newStatus
+	// Remember Progressing/Available transitions before the old status is overwritten.
+	dc.recordConditionTransitions(ctx, d, &d.Status, &newStatus)
+
 	// 최종적으로 할당요청 상태로 바꾸도록 apiserver에 호출한다.
-	fmt.Println("newDeployment-----------: ", newDeployment)
-	_, err := dc.client.AppsV1().Deployments(newDeployment.Namespace).UpdateStatus(context.TODO(), newDeployment, metav1.UpdateOptions{})
-	return err
+	fmt.Println("newDeployment-----------: ", d)
+	return dc.writeDeploymentStatus(ctx, d, newStatus)
+}
+
+// maxConditionHistoryEntries bounds the condition-history annotation.
+const maxConditionHistoryEntries = 10
+
+// recordConditionTransitions appends Progressing and Available condition transitions
+// (changed status or reason) to the bounded condition-history annotation, written with a
+// merge patch so it never conflicts with the status update that follows.
+func (dc *DeploymentController) recordConditionTransitions(ctx context.Context, d *apps.Deployment, oldStatus, newStatus *apps.DeploymentStatus) {
+	var entries []string
+	for _, conditionType := range []apps.DeploymentConditionType{apps.DeploymentProgressing, apps.DeploymentAvailable} {
+		oldCondition := util.GetDeploymentCondition(*oldStatus, conditionType)
+		newCondition := util.GetDeploymentCondition(*newStatus, conditionType)
+		// Only real transitions count; the initial appearance of a condition is not
+		// history worth keeping.
+		if newCondition == nil || oldCondition == nil {
+			continue
+		}
+		if oldCondition.Status == newCondition.Status && oldCondition.Reason == newCondition.Reason {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s=%s/%s@%s", conditionType, newCondition.Status, newCondition.Reason, dc.now().UTC().Format(time.RFC3339)))
+	}
+	if len(entries) == 0 {
+		return
+	}
+	var history []string
+	if existing := d.Annotations[util.ConditionHistoryAnnotation]; existing != "" {
+		history = strings.Split(existing, ",")
+	}
+	history = append(history, entries...)
+	if len(history) > maxConditionHistoryEntries {
+		history = history[len(history)-maxConditionHistoryEntries:]
+	}
+	dc.patchProgressAnnotation(ctx, d, util.ConditionHistoryAnnotation, strings.Join(history, ","))
+}
+
+// deadlineWarningState remembers the highest escalation threshold already warned about
+// for one stall of a deployment (identified by the Progressing condition's last update).
+type deadlineWarningState struct {
+	stalledSince  time.Time
+	warnedPercent int
+}
+
+// deadlineWarningThresholds are the percentages of progressDeadlineSeconds at which the
+// controller emits escalating warnings while no progress is made.
+var deadlineWarningThresholds = []int{50, 80, 95}
+
+// warnApproachingDeadline emits escalating warning events at fixed fractions of the
+// progress deadline, so operators hear about a stalling rollout before
+// ProgressDeadlineExceeded fires. Each threshold is warned about once per stall, and the
+// deployment is requeued for the next threshold.
+func (dc *DeploymentController) warnApproachingDeadline(d *apps.Deployment, condition *apps.DeploymentCondition) {
+	if dc.deadlineWarnings == nil || condition.Reason == util.TimedOutReason {
+		return
+	}
+	total := time.Duration(*d.Spec.ProgressDeadlineSeconds) * time.Second
+	if total <= 0 {
+		return
+	}
+	elapsed := dc.now().Sub(condition.LastUpdateTime.Time)
+	percent := int(elapsed * 100 / total)
+
+	key := d.Namespace + "/" + d.Name
+	dc.deadlineWarningMu.Lock()
+	state := dc.deadlineWarnings[key]
+	if !state.stalledSince.Equal(condition.LastUpdateTime.Time) {
+		// Progress was made since the last warning; this is a new stall.
+		state = deadlineWarningState{stalledSince: condition.LastUpdateTime.Time}
+	}
+	warn := 0
+	next := 0
+	for _, threshold := range deadlineWarningThresholds {
+		if percent >= threshold && threshold > state.warnedPercent {
+			warn = threshold
+		}
+		if next == 0 && percent < threshold {
+			next = threshold
+		}
+	}
+	if warn > 0 {
+		state.warnedPercent = warn
+	}
+	dc.deadlineWarnings[key] = state
+	dc.deadlineWarningMu.Unlock()
+
+	if warn > 0 {
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "ApproachingProgressDeadline",
+			"%d%% of the progress deadline (%v) elapsed without progress", warn, total)
+	}
+	if next > 0 {
+		// Wake up for the next escalation threshold.
+		dc.enqueueAfter(d, total*time.Duration(next)/100-elapsed+time.Second)
+	}
+}
+
+// progressSample remembers the last observed availability of a deployment's new replica
+// set, for estimating the completion time of its rollout.
+type progressSample struct {
+	availableReplicas int32
+	observedAt        time.Time
+}
+
+// etaPublishThreshold is how much a new estimate must differ from the published one
+// before the annotation is rewritten, bounding write amplification.
+const etaPublishThreshold = 15 * time.Second
+
+// publishRolloutETA maintains the estimated-completion annotation for an in-flight
+// rollout, based on the rate at which new-revision pods became available between syncs.
+// The estimate is removed once the rollout completes and only rewritten when it moved by
+// more than a threshold. Everything here is best effort.
+func (dc *DeploymentController) publishRolloutETA(ctx context.Context, d *apps.Deployment, newRS *apps.ReplicaSet, newStatus *apps.DeploymentStatus) {
+	if dc.progressSamples == nil {
+		return
+	}
+	key := d.Namespace + "/" + d.Name
+	published, hasPublished := d.Annotations[util.EstimatedCompletionAnnotation]
+
+	if newRS == nil || util.DeploymentComplete(d, newStatus) {
+		dc.progressSampleMu.Lock()
+		delete(dc.progressSamples, key)
+		dc.progressSampleMu.Unlock()
+		if hasPublished {
+			dc.patchProgressAnnotation(ctx, d, util.EstimatedCompletionAnnotation, "")
+		}
+		return
+	}
+
+	now := dc.now()
+	available := newRS.Status.AvailableReplicas
+	dc.progressSampleMu.Lock()
+	last, seen := dc.progressSamples[key]
+	if !seen || available < last.availableReplicas {
+		// First observation of this rollout (or a rollback shrank availability);
+		// start a fresh measurement.
+		dc.progressSamples[key] = progressSample{availableReplicas: available, observedAt: now}
+		dc.progressSampleMu.Unlock()
+		return
+	}
+	if available == last.availableReplicas {
+		dc.progressSampleMu.Unlock()
+		return
+	}
+	dc.progressSamples[key] = progressSample{availableReplicas: available, observedAt: now}
+	dc.progressSampleMu.Unlock()
+
+	remaining := *(d.Spec.Replicas) - available
+	if remaining <= 0 {
+		return
+	}
+	rate := float64(available-last.availableReplicas) / now.Sub(last.observedAt).Seconds()
+	if rate <= 0 {
+		return
+	}
+	eta := now.Add(time.Duration(float64(remaining)/rate) * time.Second)
+	if hasPublished {
+		if publishedTime, err := time.Parse(time.RFC3339, published); err == nil {
+			if delta := eta.Sub(publishedTime); delta > -etaPublishThreshold && delta < etaPublishThreshold {
+				return
+			}
+		}
+	}
+	dc.patchProgressAnnotation(ctx, d, util.EstimatedCompletionAnnotation, eta.UTC().Format(time.RFC3339))
+}
+
+// publishProgressPercent maintains the progress-percent annotation: the percentage of
+// desired replicas that are updated and available on the new replica set, written only
+// when the value changes and removed once the rollout completes.
+func (dc *DeploymentController) publishProgressPercent(ctx context.Context, d *apps.Deployment, newRS *apps.ReplicaSet, newStatus *apps.DeploymentStatus) {
+	published, hasPublished := d.Annotations[util.ProgressPercentAnnotation]
+	// The percentage is only meaningful while old-revision capacity is actually being
+	// migrated; otherwise any published value is cleaned up.
+	if newRS == nil || util.DeploymentComplete(d, newStatus) || newStatus.Replicas <= newStatus.UpdatedReplicas {
+		if hasPublished {
+			dc.patchProgressAnnotation(ctx, d, util.ProgressPercentAnnotation, "")
+		}
+		return
+	}
+	desired := *(d.Spec.Replicas)
+	if desired == 0 {
+		return
+	}
+	percent := newRS.Status.AvailableReplicas * 100 / desired
+	if percent > 100 {
+		percent = 100
+	}
+	value := strconv.FormatInt(int64(percent), 10)
+	if hasPublished && published == value {
+		return
+	}
+	dc.patchProgressAnnotation(ctx, d, util.ProgressPercentAnnotation, value)
+}
+
+// publishTerminatingReplicas maintains the terminating-replicas annotation: the number
+// of the deployment's pods that have a deletion timestamp but are still around. The
+// annotation is removed when nothing is terminating.
+func (dc *DeploymentController) publishTerminatingReplicas(ctx context.Context, d *apps.Deployment) {
+	if dc.podLister == nil {
+		return
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return
+	}
+	pods, err := dc.podLister.Pods(d.Namespace).List(selector)
+	if err != nil {
+		return
+	}
+	terminating := 0
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.Kind != "ReplicaSet" {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			terminating++
+		}
+	}
+	published, hasPublished := d.Annotations[util.TerminatingReplicasAnnotation]
+	if terminating == 0 {
+		if hasPublished {
+			dc.patchProgressAnnotation(ctx, d, util.TerminatingReplicasAnnotation, "")
+		}
+		return
+	}
+	value := strconv.Itoa(terminating)
+	if hasPublished && published == value {
+		return
+	}
+	dc.patchProgressAnnotation(ctx, d, util.TerminatingReplicasAnnotation, value)
+}
+
+// publishRevisionBreakdown maintains the revision-breakdown annotation: one entry per
+// active replica set with its replicas, ready and available counts, keyed by the
+// pod-template-hash. The annotation is removed once at most one revision is active.
+func (dc *DeploymentController) publishRevisionBreakdown(ctx context.Context, d *apps.Deployment, allRSs []*apps.ReplicaSet) {
+	var parts []string
+	active := 0
+	for _, rs := range allRSs {
+		if rs == nil || *(rs.Spec.Replicas) == 0 {
+			continue
+		}
+		active++
+		key := rs.Labels[apps.DefaultDeploymentUniqueLabelKey]
+		if key == "" {
+			key = rs.Name
+		}
+		parts = append(parts, fmt.Sprintf("%s=%d/%d/%d", key, *(rs.Spec.Replicas), rs.Status.ReadyReplicas, rs.Status.AvailableReplicas))
+	}
+	published, hasPublished := d.Annotations[util.RevisionBreakdownAnnotation]
+	if active <= 1 {
+		if hasPublished {
+			dc.patchProgressAnnotation(ctx, d, util.RevisionBreakdownAnnotation, "")
+		}
+		return
+	}
+	sort.Strings(parts)
+	value := strings.Join(parts, ";")
+	if hasPublished && published == value {
+		return
+	}
+	dc.patchProgressAnnotation(ctx, d, util.RevisionBreakdownAnnotation, value)
+}
+
+// strategySubStatus is the JSON carried by the strategy-status annotation for the
+// annotation-driven strategies.
+type strategySubStatus struct {
+	Mode string `json:"mode"`
+	// BatchSize and CompletedBatches describe a batched rollout.
+	BatchSize        int32 `json:"batchSize,omitempty"`
+	CompletedBatches int32 `json:"completedBatches,omitempty"`
+	// NewPortion/OldPortion describe a dual-revision split.
+	NewPortion int32 `json:"newPortion,omitempty"`
+	OldPortion int32 `json:"oldPortion,omitempty"`
+	// Holds lists what currently blocks promotion (AwaitingApproval, AwaitingAnalysis,
+	// RolloutHeld reasons).
+	Holds []string `json:"holds,omitempty"`
+}
+
+// publishStrategyStatus maintains the strategy-status annotation for deployments using
+// one of the richer annotation-driven strategies; plain rolling updates don't carry it.
+func (dc *DeploymentController) publishStrategyStatus(ctx context.Context, d *apps.Deployment, newRS *apps.ReplicaSet, newStatus *apps.DeploymentStatus) {
+	subStatus := strategySubStatus{}
+	if batchSize, ok := util.GetRollingBatchSize(d); ok {
+		subStatus.Mode = "BatchedRollingUpdate"
+		subStatus.BatchSize = batchSize
+		if newRS != nil && batchSize > 0 {
+			subStatus.CompletedBatches = newRS.Status.AvailableReplicas / batchSize
+		}
+	}
+	if newPortion, oldPortion, ok := util.GetDualRevisionRatio(d); ok {
+		subStatus.Mode = "DualRevision"
+		subStatus.NewPortion = newPortion
+		subStatus.OldPortion = oldPortion
+	}
+	for _, conditionType := range []apps.DeploymentConditionType{util.DeploymentAwaitingApproval, util.DeploymentRolloutHeld} {
+		if condition := util.GetDeploymentCondition(*newStatus, conditionType); condition != nil && condition.Status == v1.ConditionTrue {
+			subStatus.Holds = append(subStatus.Holds, condition.Reason)
+		}
+	}
+
+	published, hasPublished := d.Annotations[util.StrategyStatusAnnotation]
+	if subStatus.Mode == "" && len(subStatus.Holds) == 0 {
+		if hasPublished {
+			dc.patchProgressAnnotation(ctx, d, util.StrategyStatusAnnotation, "")
+		}
+		return
+	}
+	if subStatus.Mode == "" {
+		subStatus.Mode = string(d.Spec.Strategy.Type)
+	}
+	data, err := json.Marshal(&subStatus)
+	if err != nil {
+		return
+	}
+	if hasPublished && published == string(data) {
+		return
+	}
+	dc.patchProgressAnnotation(ctx, d, util.StrategyStatusAnnotation, string(data))
+}
+
+// publishRevisionHashes maintains the current/previous revision hash annotations: the
+// pod-template-hash of the new replica set and of the latest old revision. Both are
+// written in a single merge patch and only when a value changed.
+func (dc *DeploymentController) publishRevisionHashes(ctx context.Context, d *apps.Deployment, newRS *apps.ReplicaSet, allRSs []*apps.ReplicaSet) {
+	currentHash := ""
+	if newRS != nil {
+		currentHash = newRS.Labels[apps.DefaultDeploymentUniqueLabelKey]
+	}
+	previousHash := ""
+	previousRevision := int64(-1)
+	for _, rs := range allRSs {
+		if rs == nil || (newRS != nil && rs.UID == newRS.UID) {
+			continue
+		}
+		if revision, err := util.Revision(rs); err == nil && revision > previousRevision {
+			if hash := rs.Labels[apps.DefaultDeploymentUniqueLabelKey]; hash != "" {
+				previousRevision = revision
+				previousHash = hash
+			}
+		}
+	}
+	if d.Annotations[util.CurrentRevisionHashAnnotation] == currentHash && d.Annotations[util.PreviousRevisionHashAnnotation] == previousHash {
+		return
+	}
+	value := func(hash string) string {
+		if hash == "" {
+			return "null"
+		}
+		return strconv.Quote(hash)
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%s,%q:%s}}}`,
+		util.CurrentRevisionHashAnnotation, value(currentHash),
+		util.PreviousRevisionHashAnnotation, value(previousHash)))
+	if _, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.V(4).Infof("Couldn't publish revision hashes for deployment %s/%s: %v", d.Namespace, d.Name, err)
+	}
+}
+
+// patchProgressAnnotation writes (or, for an empty value, removes) one of the
+// controller-managed progress annotations with a merge patch.
+func (dc *DeploymentController) patchProgressAnnotation(ctx context.Context, d *apps.Deployment, annotationKey, value string) {
+	var patch []byte
+	if value == "" {
+		patch = []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, annotationKey))
+	} else {
+		patch = []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, annotationKey, value))
+	}
+	if _, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.V(4).Infof("Couldn't publish progress annotation %q for deployment %s/%s: %v", annotationKey, d.Namespace, d.Name, err)
+	}
+}
+
+// podFailureSummary aggregates the dominant failure reasons of the new replica set's
+// unready pods (e.g. ImagePullBackOff, CreateContainerConfigError, Unschedulable) into a
+// short breakdown for the Progressing condition message.
+func (dc *DeploymentController) podFailureSummary(d *apps.Deployment, newRS *apps.ReplicaSet) string {
+	if newRS == nil || dc.podLister == nil {
+		return ""
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return ""
+	}
+	pods, err := dc.podLister.Pods(d.Namespace).List(selector)
+	if err != nil {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.UID != newRS.UID {
+			continue
+		}
+		if podutil.IsPodReady(pod) {
+			continue
+		}
+		reason := ""
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+				reason = status.State.Waiting.Reason
+				break
+			}
+		}
+		if reason == "" {
+			if cond := podutil.GetPodReadyCondition(pod.Status); cond != nil && cond.Reason != "" {
+				reason = cond.Reason
+			}
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse && cond.Reason != "" {
+					reason = cond.Reason
+					break
+				}
+			}
+		}
+		if reason != "" {
+			counts[reason]++
+		}
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] == counts[reasons[j]] {
+			return reasons[i] < reasons[j]
+		}
+		return counts[reasons[i]] > counts[reasons[j]]
+	})
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		parts = append(parts, fmt.Sprintf("%s x%d", reason, counts[reason]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// replicaSetCreateBlockedCondition detects pod creation blocked by ResourceQuota or
+// LimitRange restrictions from the replica sets' failure conditions and turns the first
+// match into a dedicated deployment condition carrying the quota message.
+func replicaSetCreateBlockedCondition(allRSs []*apps.ReplicaSet) *apps.DeploymentCondition {
+	for _, rs := range allRSs {
+		if rs == nil {
+			continue
+		}
+		for _, c := range rs.Status.Conditions {
+			if c.Type != apps.ReplicaSetReplicaFailure || c.Status != v1.ConditionTrue || c.Reason != "FailedCreate" {
+				continue
+			}
+			message := strings.ToLower(c.Message)
+			if !strings.Contains(message, "quota") && !strings.Contains(message, "limit") {
+				continue
+			}
+			return util.NewDeploymentCondition(util.DeploymentReplicaSetCreateBlocked, v1.ConditionTrue, "BlockedByQuota",
+				fmt.Sprintf("Replica set %q cannot create pods: %s", rs.Name, c.Message))
+		}
+	}
+	return nil
+}
+
+// imagePullBlockedCondition reports new-revision pods stuck pulling their image
+// (ErrImagePull/ImagePullBackOff) as a dedicated condition naming the failing image.
+func (dc *DeploymentController) imagePullBlockedCondition(d *apps.Deployment, newRS *apps.ReplicaSet) *apps.DeploymentCondition {
+	if newRS == nil || dc.podLister == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	pods, err := dc.podLister.Pods(d.Namespace).List(selector)
+	if err != nil {
+		return nil
+	}
+	failingPods := 0
+	failingImage := ""
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.UID != newRS.UID {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting == nil {
+				continue
+			}
+			if status.State.Waiting.Reason == "ErrImagePull" || status.State.Waiting.Reason == "ImagePullBackOff" {
+				failingPods++
+				failingImage = status.Image
+				break
+			}
+		}
+	}
+	if failingPods == 0 {
+		return nil
+	}
+	return util.NewDeploymentCondition(util.DeploymentImagePullBlocked, v1.ConditionTrue, "ImagePullFailure",
+		fmt.Sprintf("%d pod(s) of replica set %q cannot pull image %q", failingPods, newRS.Name, failingImage))
 }
 
 // getReplicaFailures will convert replica failure conditions from replica sets
@@ -173,6 +714,9 @@ func (dc *DeploymentController) requeueStuckDeployment(d *apps.Deployment, newSt
 	if util.DeploymentComplete(d, &newStatus) || currentCond.Reason == util.TimedOutReason {
 		return time.Duration(-1)
 	}
+	// Give operators advance notice while the deadline approaches without progress.
+	dc.warnApproachingDeadline(d, currentCond)
+
 	// If there is no sign of progress at this point then there is a high chance that the
 	// deployment is stuck. We should resync this deployment at some point in the future[1]
 	// and check whether it has timed out. We definitely need this, otherwise we depend on the
@@ -188,7 +732,15 @@ func (dc *DeploymentController) requeueStuckDeployment(d *apps.Deployment, newSt
 	// progressDeadlineSeconds: 600 (10 minutes)
 	//
 	// lastUpdated + progressDeadlineSeconds - now => 00:00:00 + 00:10:00 - 00:03:00 => 07:00
-	after := currentCond.LastUpdateTime.Time.Add(time.Duration(*d.Spec.ProgressDeadlineSeconds) * time.Second).Sub(nowFn())
+	after := currentCond.LastUpdateTime.Time.Add(time.Duration(*d.Spec.ProgressDeadlineSeconds) * time.Second).Sub(dc.now())
+	// Clamp the deadline-derived delay to the configured re-check cadence, trading API
+	// churn against stuck-rollout detection latency.
+	if dc.progressRecheckMaxInterval > 0 && after > dc.progressRecheckMaxInterval {
+		after = dc.progressRecheckMaxInterval
+	}
+	if dc.progressRecheckMinInterval > 0 && after < dc.progressRecheckMinInterval {
+		after = dc.progressRecheckMinInterval
+	}
 	// If the remaining time is less than a second, then requeue the deployment immediately.
 	// Make it ratelimited so we stay on the safe side, eventually the Deployment should
 	// transition either to a Complete or to a TimedOut condition.
@@ -17,6 +17,7 @@ limitations under the License.
 package deployment
 
 import (
+	"context"
 	"math"
 	"testing"
 	"time"
@@ -330,7 +331,7 @@ func TestSyncRolloutStatus(t *testing.T) {
 				test.allRSs = append(test.allRSs, test.newRS)
 			}
 
-			err := dc.syncRolloutStatus(test.allRSs, test.newRS, test.d)
+			err := dc.syncRolloutStatus(context.TODO(), test.allRSs, test.newRS, test.d)
 			if err != nil {
 				t.Error(err)
 			}
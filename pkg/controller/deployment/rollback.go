@@ -17,9 +17,14 @@ limitations under the License.
 package deployment
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -31,53 +36,341 @@ import (
 )
 
 // rollback the deployment to the specified revision. In any case cleanup the rollback spec.
-func (dc *DeploymentController) rollback(d *apps.Deployment, rsList []*apps.ReplicaSet) error {
-	newRS, allOldRSs, err := dc.getAllReplicaSetsAndSyncRevision(d, rsList, true)
+func (dc *DeploymentController) rollback(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+	newRS, allOldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, true)
 	if err != nil {
 		return err
 	}
 
 	allRSs := append(allOldRSs, newRS)
+	// Outcome conditions are only reported for the structured rollback request path;
+	// the deprecated annotation path keeps its event-only contract.
+	structuredRequest := getRollbackRequest(d) != nil
 	rollbackTo := getRollbackTo(d)
 	// If rollback revision is 0, rollback to the last revision
-	if rollbackTo.Revision == 0 {
+	autoRollback := rollbackTo.Revision == 0
+	if autoRollback {
 		if rollbackTo.Revision = deploymentutil.LastRevision(allRSs); rollbackTo.Revision == 0 {
 			// If we still can't find the last revision, gives up rollback
 			dc.emitRollbackWarningEvent(d, deploymentutil.RollbackRevisionNotFound, "Unable to find last revision.")
 			// Gives up rollback
-			return dc.updateDeploymentAndClearRollbackTo(d)
+			return dc.updateDeploymentAndClearRollbackTo(ctx, d)
 		}
 	}
-	for _, rs := range allRSs {
-		v, err := deploymentutil.Revision(rs)
-		if err != nil {
-			klog.V(4).Infof("Unable to extract revision from deployment's replica set %q: %v", rs.Name, err)
-			continue
+	// Automatic rollbacks may fall through to the next eligible revision when the
+	// requested one is marked bad; explicit requests are rejected instead.
+	target, wasBlocked := deploymentutil.FindRollbackTarget(allRSs, rollbackTo.Revision, autoRollback)
+	if target != nil {
+		v, _ := deploymentutil.Revision(target)
+		klog.V(4).Infof("Found replica set %q with desired revision %d", target.Name, v)
+		if wasBlocked {
+			dc.emitRollbackWarningEvent(d, deploymentutil.RollbackBlockedReason,
+				fmt.Sprintf("Revision %d is marked bad; falling back to revision %d", rollbackTo.Revision, v))
+		}
+		// Enforce the rollback eligibility window, if one is declared.
+		if violation := dc.rollbackWindowViolation(d, target, v); violation != "" {
+			dc.emitRollbackWarningEvent(d, deploymentutil.RollbackOutOfWindowReason, violation)
+			return dc.clearRollbackWithBlockedCondition(ctx, d, deploymentutil.RollbackOutOfWindowReason, violation)
 		}
-		if v == rollbackTo.Revision {
-			klog.V(4).Infof("Found replica set %q with desired revision %d", rs.Name, v)
-			// rollback by copying podTemplate.Spec from the replica set
-			// revision number will be incremented during the next getAllReplicaSetsAndSyncRevision call
-			// no-op if the spec matches current deployment's podTemplate.Spec
-			performedRollback, err := dc.rollbackToTemplate(d, rs)
-			if performedRollback && err == nil {
-				dc.emitRollbackNormalEvent(d, fmt.Sprintf("Rolled back deployment %q to revision %d", d.Name, rollbackTo.Revision))
+		// A dry-run request only previews the rollback: report what would change and
+		// clear the request without touching the deployment's template.
+		if d.Annotations[deploymentutil.RollbackDryRunAnnotation] == "true" {
+			diff := deploymentutil.TemplateDiffSummary(&d.Spec.Template, &target.Spec.Template)
+			if diff == "" {
+				diff = "nothing; the revision contains the same template"
 			}
-			return err
+			dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "RollbackDryRun",
+				"Rolling back deployment %q to revision %d would change: %s", d.Name, v, diff)
+			return dc.updateDeploymentAndClearRollbackTo(ctx, d)
+		}
+		// Summarize what the rollback is about to change before applying it.
+		if diff := deploymentutil.TemplateDiffSummary(&d.Spec.Template, &target.Spec.Template); diff != "" {
+			dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "RollbackDiff",
+				"Rolling back deployment %q to revision %d will change: %s", d.Name, v, diff)
+		}
+		// Optionally restore the replica count that was recorded for the target revision
+		// instead of keeping the current one. The change is persisted together with the
+		// template update below.
+		if request := getRollbackRequest(d); request != nil && request.KeepReplicas != nil && !*request.KeepReplicas {
+			if desired, ok := deploymentutil.GetDesiredReplicasAnnotation(target); ok && desired != *(d.Spec.Replicas) {
+				dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "RollbackRestoredReplicas",
+					"Restoring replica count %d recorded for revision %d (was %d)", desired, v, *(d.Spec.Replicas))
+				*(d.Spec.Replicas) = desired
+			}
+		}
+		// rollback by copying podTemplate.Spec from the replica set
+		// revision number will be incremented during the next getAllReplicaSetsAndSyncRevision call
+		// no-op if the spec matches current deployment's podTemplate.Spec
+		performedRollback, err := dc.rollbackToTemplate(ctx, d, target)
+		baking := false
+		if performedRollback && err == nil {
+			if _, baking = d.Annotations[deploymentutil.RollbackVerifyUntilAnnotation]; baking {
+				dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "RollbackVerifying",
+					"Rolled back deployment %q to revision %d; verifying for the configured bake time", d.Name, v)
+			} else {
+				dc.emitRollbackNormalEvent(d, fmt.Sprintf("Rolled back deployment %q to revision %d", d.Name, v))
+			}
+			// An aborted rollout may have left several stale replica sets partially
+			// scaled. Move their capacity back onto the revision we rolled back to
+			// instead of letting proportional scaling keep the patchwork around.
+			err = dc.consolidateOnStableReplicaSet(ctx, d, allRSs, target)
+		}
+		if err == nil && structuredRequest && !baking {
+			err = dc.recordRollbackOutcome(ctx, d, v1.ConditionTrue, deploymentutil.RollbackDone,
+				fmt.Sprintf("Rollback to revision %d complete", v))
 		}
+		return err
+	}
+	if wasBlocked {
+		message := fmt.Sprintf("Revision %d is marked bad and no eligible revision remains to fall back to", rollbackTo.Revision)
+		dc.emitRollbackWarningEvent(d, deploymentutil.RollbackBlockedReason, message)
+		return dc.clearRollbackWithBlockedCondition(ctx, d, deploymentutil.RollbackBlockedReason, message)
+	}
+	if d.Annotations[deploymentutil.RollbackDryRunAnnotation] == "true" {
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "RollbackDryRun",
+			"Rolling back deployment %q to revision %d would fail: revision not found. Retained revisions: %v", d.Name, rollbackTo.Revision, deploymentutil.RetainedRevisions(allRSs))
+		return dc.updateDeploymentAndClearRollbackTo(ctx, d)
 	}
-	dc.emitRollbackWarningEvent(d, deploymentutil.RollbackRevisionNotFound, "Unable to find the revision to rollback to.")
+	// No retained replica set carries the revision; the ControllerRevision history may
+	// still have it.
+	if handled, err := dc.rollbackToControllerRevision(ctx, d, rollbackTo.Revision); handled || err != nil {
+		return err
+	}
+	dc.emitRollbackWarningEvent(d, deploymentutil.RollbackRevisionNotFound,
+		fmt.Sprintf("Unable to find the revision to rollback to. Retained revisions: %v", deploymentutil.RetainedRevisions(allRSs)))
 	// Gives up rollback
-	return dc.updateDeploymentAndClearRollbackTo(d)
+	if err := dc.updateDeploymentAndClearRollbackTo(ctx, d); err != nil {
+		return err
+	}
+	if !structuredRequest {
+		return nil
+	}
+	return dc.recordRollbackOutcome(ctx, d, v1.ConditionFalse, deploymentutil.RollbackRevisionNotFound,
+		fmt.Sprintf("Revision %d not found", rollbackTo.Revision))
+}
+
+// enforcePinnedRevision continuously reverts a deployment that is pinned to a revision:
+// when the live pod template drifts from the pinned revision's template and no rollback
+// is already pending, a rollback request to the pinned revision is filed. The resulting
+// update requeues the deployment, which then performs the reversion through the regular
+// rollback path.
+func (dc *DeploymentController) enforcePinnedRevision(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+	pinned, ok := deploymentutil.GetPinnedRevision(d)
+	if !ok || getRollbackTo(d) != nil {
+		return nil
+	}
+	target, _ := deploymentutil.FindRollbackTarget(rsList, pinned, false)
+	if target == nil {
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "PinnedRevisionNotFound",
+			"Deployment is pinned to revision %d but no replica set carries that revision", pinned)
+		return nil
+	}
+	if deploymentutil.EqualIgnoreHash(&d.Spec.Template, &target.Spec.Template) {
+		return nil
+	}
+
+	setRollbackTo(d, &extensions.RollbackConfig{Revision: pinned})
+	d.Annotations[deploymentutil.RollbackTriggerAnnotation] = "RevisionPinned"
+	updated, err := dc.client.AppsV1().Deployments(d.Namespace).Update(ctx, d, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	// The sync continues with this object; keep it at the server's resourceVersion.
+	if updated != nil {
+		*d = *updated
+	}
+	dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "RevisionPinned",
+		"Reverting drifted template back to pinned revision %d", pinned)
+	dc.auditMutation("Deployment", d.Namespace, d.Name, "update", "requested rollback", "RevisionPinned")
+	return nil
+}
+
+// rollbackNotification is the payload POSTed to the rollback webhook.
+type rollbackNotification struct {
+	Namespace    string      `json:"namespace"`
+	Name         string      `json:"name"`
+	FromRevision int64       `json:"fromRevision"`
+	ToRevision   int64       `json:"toRevision"`
+	Trigger      string      `json:"trigger"`
+	Time         metav1.Time `json:"time"`
+}
+
+// notifyRollbackWebhook posts a notification about an automatic rollback to the
+// configured webhook, if any. User-initiated rollbacks (no trigger recorded) are not
+// reported. The POST happens asynchronously and failures are only logged; notification
+// delivery never blocks or fails the rollback itself.
+func (dc *DeploymentController) notifyRollbackWebhook(d *apps.Deployment, fromRevision, toRevision int64, trigger string) {
+	if dc.rollbackWebhookURL == "" || trigger == "" {
+		return
+	}
+	payload, err := json.Marshal(rollbackNotification{
+		Namespace:    d.Namespace,
+		Name:         d.Name,
+		FromRevision: fromRevision,
+		ToRevision:   toRevision,
+		Trigger:      trigger,
+		Time:         metav1.Now(),
+	})
+	if err != nil {
+		klog.Errorf("Couldn't marshal rollback notification for deployment %s/%s: %v", d.Namespace, d.Name, err)
+		return
+	}
+	url := dc.rollbackWebhookURL
+	go func() {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			klog.Warningf("Couldn't deliver rollback notification for deployment %s/%s: %v", d.Namespace, d.Name, err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// recordRollbackOutcome reports the outcome of a handled rollback request in the
+// RollbackComplete condition.
+func (dc *DeploymentController) recordRollbackOutcome(ctx context.Context, d *apps.Deployment, status v1.ConditionStatus, reason, message string) error {
+	condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentRollbackComplete, status, reason, message)
+	deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+	_, err := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
+	return err
+}
+
+// verifyRollbackBake drives the verification phase of a two-phase rollback. It returns
+// true when it concluded the verification (successfully or not) and the sync should stop
+// here; while the bake is still running the deployment stays paused and is requeued for
+// the remainder.
+func (dc *DeploymentController) verifyRollbackBake(ctx context.Context, d *apps.Deployment) (bool, error) {
+	annotationValue, ok := d.Annotations[deploymentutil.RollbackVerifyUntilAnnotation]
+	if !ok {
+		return false, nil
+	}
+	until, err := time.Parse(time.RFC3339, annotationValue)
+	if err != nil {
+		// Unparseable state; drop it rather than wedging the deployment in paused state.
+		delete(d.Annotations, deploymentutil.RollbackVerifyUntilAnnotation)
+		delete(d.Annotations, deploymentutil.RollbackVerifyAvailableAnnotation)
+		return true, dc.updateDeploymentForBake(ctx, d)
+	}
+
+	// The rollback was most likely triggered by a loss of availability, so the
+	// Available=False condition present when the bake starts belongs to the bad
+	// revision. Only once the restored revision has become available does losing
+	// availability again fail the verification.
+	available := deploymentutil.GetDeploymentCondition(d.Status, apps.DeploymentAvailable)
+	becameAvailable := d.Annotations[deploymentutil.RollbackVerifyAvailableAnnotation] == "true"
+	if !becameAvailable && available != nil && available.Status == v1.ConditionTrue {
+		d.Annotations[deploymentutil.RollbackVerifyAvailableAnnotation] = "true"
+		if err := dc.updateDeploymentForBake(ctx, d); err != nil {
+			return true, err
+		}
+		becameAvailable = true
+	}
+	if becameAvailable && available != nil && available.Status == v1.ConditionFalse {
+		// The restored revision did not stay healthy: keep the deployment paused and
+		// surface a distinct failure.
+		delete(d.Annotations, deploymentutil.RollbackVerifyUntilAnnotation)
+		delete(d.Annotations, deploymentutil.RollbackVerifyAvailableAnnotation)
+		if err := dc.updateDeploymentForBake(ctx, d); err != nil {
+			return true, err
+		}
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "RollbackVerificationFailed",
+			"Deployment %q lost availability during the rollback verification bake", d.Name)
+		return true, dc.recordRollbackOutcome(ctx, d, v1.ConditionFalse, "RollbackVerificationFailed",
+			"The restored revision lost availability during the verification bake")
+	}
+
+	now := dc.now()
+	if now.Before(until) {
+		dc.enqueueAfter(d, until.Sub(now)+time.Second)
+		return false, nil
+	}
+	if !becameAvailable {
+		// The bake expired without the restored revision ever becoming available.
+		delete(d.Annotations, deploymentutil.RollbackVerifyUntilAnnotation)
+		delete(d.Annotations, deploymentutil.RollbackVerifyAvailableAnnotation)
+		if err := dc.updateDeploymentForBake(ctx, d); err != nil {
+			return true, err
+		}
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "RollbackVerificationFailed",
+			"Deployment %q never became available during the rollback verification bake", d.Name)
+		return true, dc.recordRollbackOutcome(ctx, d, v1.ConditionFalse, "RollbackVerificationFailed",
+			"The restored revision never became available during the verification bake")
+	}
+
+	// Verified: resume the deployment and report the rollback as done.
+	delete(d.Annotations, deploymentutil.RollbackVerifyUntilAnnotation)
+	delete(d.Annotations, deploymentutil.RollbackVerifyAvailableAnnotation)
+	d.Spec.Paused = false
+	if err := dc.updateDeploymentForBake(ctx, d); err != nil {
+		return true, err
+	}
+	dc.emitRollbackNormalEvent(d, fmt.Sprintf("Rollback of deployment %q verified after bake", d.Name))
+	dc.auditMutation("Deployment", d.Namespace, d.Name, "update", "resumed", "RollbackVerified")
+	return true, dc.recordRollbackOutcome(ctx, d, v1.ConditionTrue, deploymentutil.RollbackDone, "Rollback verified after bake")
+}
+
+// updateDeploymentForBake persists bake bookkeeping on the deployment and refreshes the
+// caller's object from the update response, so follow-up status writes don't go out with
+// a stale resourceVersion.
+func (dc *DeploymentController) updateDeploymentForBake(ctx context.Context, d *apps.Deployment) error {
+	updated, err := dc.client.AppsV1().Deployments(d.Namespace).Update(ctx, d, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	if updated != nil {
+		*d = *updated
+	}
+	return nil
+}
+
+// rollbackWindowViolation checks the rollback eligibility window declared in the
+// deployment's annotations and returns a non-empty explanation when the target revision
+// is too old or too many revisions back.
+func (dc *DeploymentController) rollbackWindowViolation(d *apps.Deployment, target *apps.ReplicaSet, targetRevision int64) string {
+	if maxAge, ok := deploymentutil.GetRollbackMaxAge(d); ok {
+		if age := dc.now().Sub(target.CreationTimestamp.Time); age > maxAge {
+			return fmt.Sprintf("Revision %d is %v old, which exceeds the rollback age limit of %v", targetRevision, age.Round(time.Second), maxAge)
+		}
+	}
+	if maxRevisions, ok := deploymentutil.GetRollbackMaxRevisions(d); ok {
+		if currentRevision, err := deploymentutil.Revision(d); err == nil && currentRevision > 0 && currentRevision-targetRevision > maxRevisions {
+			return fmt.Sprintf("Revision %d is %d revisions behind the current revision %d, which exceeds the limit of %d", targetRevision, currentRevision-targetRevision, currentRevision, maxRevisions)
+		}
+	}
+	return ""
+}
+
+// clearRollbackWithBlockedCondition clears a rejected rollback request and records a
+// RollbackBlocked condition explaining the rejection.
+func (dc *DeploymentController) clearRollbackWithBlockedCondition(ctx context.Context, d *apps.Deployment, reason, message string) error {
+	if err := dc.updateDeploymentAndClearRollbackTo(ctx, d); err != nil {
+		return err
+	}
+	condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentRollbackBlocked, v1.ConditionTrue, reason, message)
+	deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+	_, err := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
+	return err
 }
 
 // rollbackToTemplate compares the templates of the provided deployment and replica set and
 // updates the deployment with the replica set template in case they are different. It also
 // cleans up the rollback spec so subsequent requeues of the deployment won't end up in here.
-func (dc *DeploymentController) rollbackToTemplate(d *apps.Deployment, rs *apps.ReplicaSet) (bool, error) {
+func (dc *DeploymentController) rollbackToTemplate(ctx context.Context, d *apps.Deployment, rs *apps.ReplicaSet) (bool, error) {
 	performedRollback := false
 	if !deploymentutil.EqualIgnoreHash(&d.Spec.Template, &rs.Spec.Template) {
 		klog.V(4).Infof("Rolling back deployment %q to template spec %+v", d.Name, rs.Spec.Template.Spec)
+		// Keep an audit trail of the rollback before the annotations are rewritten below.
+		fromRevision, _ := deploymentutil.Revision(d)
+		toRevision, _ := deploymentutil.Revision(rs)
+		trigger := d.Annotations[deploymentutil.RollbackTriggerAnnotation]
+		delete(d.Annotations, deploymentutil.RollbackTriggerAnnotation)
+		deploymentutil.RecordRollback(d, fromRevision, toRevision, trigger, dc.now())
+		dc.notifyRollbackWebhook(d, fromRevision, toRevision, trigger)
+		if trigger == "" {
+			trigger = "user"
+		}
+		rollbacksTotal.WithLabelValues(d.Namespace, trigger).Inc()
 		deploymentutil.SetFromReplicaSetTemplate(d, rs.Spec.Template)
 		// set RS (the old RS we'll rolling back to) annotations back to the deployment;
 		// otherwise, the deployment's current annotations (should be the same as current new RS) will be copied to the RS after the rollback.
@@ -91,14 +384,66 @@ func (dc *DeploymentController) rollbackToTemplate(d *apps.Deployment, rs *apps.
 		// If we don't copy the annotations back from RS to deployment on rollback, the Deployment will stay as {change-cause:edit},
 		// and new RS1 becomes {change-cause:edit} (copied from deployment after rollback), old RS2 {change-cause:edit}, which is not correct.
 		deploymentutil.SetDeploymentAnnotationsTo(d, rs)
+		// Companion annotations snapshotted with the revision are restored as well.
+		deploymentutil.RestoreCompanionAnnotations(d, rs)
+		// A rollback bake makes this a two-phase rollback: pause for verification and
+		// only report completion once the restored revision stayed available.
+		if bake, ok := deploymentutil.GetRollbackBake(d); ok {
+			d.Spec.Paused = true
+			d.Annotations[deploymentutil.RollbackVerifyUntilAnnotation] = dc.now().Add(bake).UTC().Format(time.RFC3339)
+		}
 		performedRollback = true
 	} else {
+		delete(d.Annotations, deploymentutil.RollbackTriggerAnnotation)
 		klog.V(4).Infof("Rolling back to a revision that contains the same template as current deployment %q, skipping rollback...", d.Name)
 		eventMsg := fmt.Sprintf("The rollback revision contains the same template as current deployment %q", d.Name)
 		dc.emitRollbackWarningEvent(d, deploymentutil.RollbackTemplateUnchanged, eventMsg)
 	}
 
-	return performedRollback, dc.updateDeploymentAndClearRollbackTo(d)
+	return performedRollback, dc.updateDeploymentAndClearRollbackTo(ctx, d)
+}
+
+// consolidateOnStableReplicaSet scales the replica set a rollback targeted back up to the
+// full deployment size and scales the partially scaled stale replica sets down to zero.
+// It only kicks in when a rollout was aborted mid-flight with capacity spread over several
+// stale revisions; the regular rolling logic then has a patchwork of partially scaled
+// replica sets it would otherwise keep proportionally, even though all of them are being
+// abandoned.
+func (dc *DeploymentController) consolidateOnStableReplicaSet(ctx context.Context, d *apps.Deployment, allRSs []*apps.ReplicaSet, stableRS *apps.ReplicaSet) error {
+	var staleRSs []*apps.ReplicaSet
+	for _, rs := range allRSs {
+		if rs == nil || rs.UID == stableRS.UID {
+			continue
+		}
+		// Only replica sets that hold a partial share of the desired capacity form a
+		// patchwork worth consolidating; a saturated replica set is still handled by
+		// the regular rolling logic.
+		if *(rs.Spec.Replicas) == 0 || *(rs.Spec.Replicas) >= *(d.Spec.Replicas) {
+			continue
+		}
+		staleRSs = append(staleRSs, rs)
+	}
+	if len(staleRSs) < 2 {
+		return nil
+	}
+
+	if *(stableRS.Spec.Replicas) != *(d.Spec.Replicas) {
+		if _, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, stableRS, *(d.Spec.Replicas), d); err != nil {
+			return err
+		}
+	}
+	consolidatedReplicas := int32(0)
+	staleNames := make([]string, 0, len(staleRSs))
+	for _, rs := range staleRSs {
+		consolidatedReplicas += *(rs.Spec.Replicas)
+		staleNames = append(staleNames, rs.Name)
+		if _, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, rs, 0, d); err != nil {
+			return err
+		}
+	}
+	dc.eventRecorder.Eventf(d, v1.EventTypeNormal, deploymentutil.RollbackConsolidated,
+		"Consolidated %d replica(s) from partially scaled replica sets %s onto %s", consolidatedReplicas, strings.Join(staleNames, ", "), stableRS.Name)
+	return nil
 }
 
 func (dc *DeploymentController) emitRollbackWarningEvent(d *apps.Deployment, reason, message string) {
@@ -112,15 +457,53 @@ func (dc *DeploymentController) emitRollbackNormalEvent(d *apps.Deployment, mess
 // updateDeploymentAndClearRollbackTo sets .spec.rollbackTo to nil and update the input deployment
 // It is assumed that the caller will have updated the deployment template appropriately (in case
 // we want to rollback).
-func (dc *DeploymentController) updateDeploymentAndClearRollbackTo(d *apps.Deployment) error {
+func (dc *DeploymentController) updateDeploymentAndClearRollbackTo(ctx context.Context, d *apps.Deployment) error {
 	klog.V(4).Infof("Cleans up rollbackTo of deployment %q", d.Name)
 	setRollbackTo(d, nil)
-	_, err := dc.client.AppsV1().Deployments(d.Namespace).Update(context.TODO(), d, metav1.UpdateOptions{})
+	updated, err := dc.client.AppsV1().Deployments(d.Namespace).Update(ctx, d, metav1.UpdateOptions{})
+	if err == nil {
+		// Continue the sync with the server's view of the object: any follow-up status
+		// write from the pre-update copy would carry a stale resourceVersion and is a
+		// guaranteed conflict on a real apiserver.
+		if updated != nil {
+			*d = *updated
+		}
+		dc.auditMutation("Deployment", d.Namespace, d.Name, "update", "cleared rollbackTo", deploymentutil.RollbackDone)
+	}
 	return err
 }
 
+// rollbackRequest is the structured rollback request carried by the rollback-request
+// annotation.
+type rollbackRequest struct {
+	// Revision to roll back to; 0 means the previous revision.
+	Revision int64 `json:"revision"`
+	// KeepReplicas, when set, controls whether the current replica count is preserved on
+	// rollback (the default) or the count recorded for the target revision is restored.
+	KeepReplicas *bool `json:"keepReplicas,omitempty"`
+}
+
+// getRollbackRequest parses the structured rollback request, if any. Malformed requests
+// are ignored and cleared with the next handled rollback.
+func getRollbackRequest(d *apps.Deployment) *rollbackRequest {
+	annotationValue, ok := d.Annotations[deploymentutil.RollbackRequestAnnotation]
+	if !ok {
+		return nil
+	}
+	request := &rollbackRequest{}
+	if err := json.Unmarshal([]byte(annotationValue), request); err != nil {
+		klog.V(2).Infof("Ignoring malformed rollback request %q of deployment %q: %v", annotationValue, d.Name, err)
+		return nil
+	}
+	return request
+}
+
 // TODO: Remove this when extensions/v1beta1 and apps/v1beta1 Deployment are dropped.
 func getRollbackTo(d *apps.Deployment) *extensions.RollbackConfig {
+	// Prefer the structured rollback request over the deprecated annotation.
+	if request := getRollbackRequest(d); request != nil {
+		return &extensions.RollbackConfig{Revision: request.Revision}
+	}
 	// Extract the annotation used for round-tripping the deprecated RollbackTo field.
 	revision := d.Annotations[apps.DeprecatedRollbackTo]
 	if revision == "" {
@@ -140,6 +523,7 @@ func getRollbackTo(d *apps.Deployment) *extensions.RollbackConfig {
 func setRollbackTo(d *apps.Deployment, rollbackTo *extensions.RollbackConfig) {
 	if rollbackTo == nil {
 		delete(d.Annotations, apps.DeprecatedRollbackTo)
+		delete(d.Annotations, deploymentutil.RollbackRequestAnnotation)
 		return
 	}
 	if d.Annotations == nil {
@@ -17,20 +17,39 @@ limitations under the License.
 package deployment
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/apis/core/helper"
 	"k8s.io/kubernetes/pkg/controller"
 	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
+	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/utils/integer"
 )
 
 // rolloutRolling implements the logic for rolling a new replica set.
-func (dc *DeploymentController) rolloutRolling(d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+func (dc *DeploymentController) rolloutRolling(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+	// Deployments that declared a steady-state dual-revision ratio are reconciled towards
+	// that split instead of rolling everything onto the new revision.
+	if newPortion, oldPortion, ok := deploymentutil.GetDualRevisionRatio(d); ok {
+		return dc.reconcileDualRevision(ctx, d, rsList, newPortion, oldPortion)
+	}
 	// 이전 replica와 현재 replica 갯수를 반환한다.
-	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(d, rsList, true)
+	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, true)
 	fmt.Println("newRS-----------: ", newRS)
 	fmt.Println("oldRSs-----------: ", oldRSs)
 	if err != nil {
@@ -38,57 +57,141 @@ func (dc *DeploymentController) rolloutRolling(d *apps.Deployment, rsList []*app
 	}
 	allRSs := append(oldRSs, newRS)
 
+	// Tell the user when their declared budgets resolve to a 0-surge/0-unavailable
+	// deadlock for this replica count; the effective behavior falls back to
+	// maxUnavailable=1, which can surprise.
+	dc.checkRolloutDeadlock(d)
+
+	// React early to crash-looping new-revision pods, if a threshold is declared.
+	if handled, err := dc.reactToCrashLoop(ctx, d, newRS); handled || err != nil {
+		return err
+	}
+
 	// Scale up, if we can.
-	scaledUp, err := dc.reconcileNewReplicaSet(allRSs, newRS, d)
+	scaledUp, err := dc.reconcileNewReplicaSet(ctx, allRSs, newRS, d)
 	fmt.Println("scaledUp-----------: ", scaledUp)
 	if err != nil {
 		return err
 	}
 	if scaledUp {
 		// Update DeploymentStatus
-		return dc.syncRolloutStatus(allRSs, newRS, d)
+		return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
+	}
+
+	// Recreate surge pods that have been unready for too long, if requested.
+	if err := dc.cleanupStuckSurgePods(ctx, d, newRS); err != nil {
+		return err
+	}
+
+	// Let a configured analysis provider veto promotion before old capacity is removed.
+	if promote, err := dc.analysisAllowsPromotion(ctx, d, newRS); err != nil {
+		return err
+	} else if !promote {
+		return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
+	}
+
+	// An approval gate blocks promotion until the new revision has been signed off.
+	if !dc.approvalGateAllowsPromotion(d, newRS) {
+		return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
 	}
 
 	// Scale down, if we can.
-	scaledDown, err := dc.reconcileOldReplicaSets(allRSs, controller.FilterActiveReplicaSets(oldRSs), newRS, d)
+	scaledDown, err := dc.reconcileOldReplicaSets(ctx, allRSs, controller.FilterActiveReplicaSets(oldRSs), newRS, d)
 	if err != nil {
 		return err
 	}
 	if scaledDown {
 		// Update DeploymentStatus
-		return dc.syncRolloutStatus(allRSs, newRS, d)
+		return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
 	}
 
 	if deploymentutil.DeploymentComplete(d, &d.Status) {
-		if err := dc.cleanupDeployment(oldRSs, d); err != nil {
-			return err
-		}
+		dc.recordCompletedRevision(ctx, d, newRS)
+		// History cleanup happens asynchronously so deleting stale replica sets can't
+		// delay the rollout sync itself.
+		dc.enqueueCleanup(d)
 	}
 
 	// Sync deployment status
 	// current state와 desired state를 맞추러 출발
-	return dc.syncRolloutStatus(allRSs, newRS, d)
+	return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
 }
 
-func (dc *DeploymentController) reconcileNewReplicaSet(allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, deployment *apps.Deployment) (bool, error) {
+func (dc *DeploymentController) reconcileNewReplicaSet(ctx context.Context, allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, deployment *apps.Deployment) (bool, error) {
 	if *(newRS.Spec.Replicas) == *(deployment.Spec.Replicas) {
 		// Scaling not required.
 		return false, nil
 	}
 	if *(newRS.Spec.Replicas) > *(deployment.Spec.Replicas) {
 		// Scale down.
-		scaled, _, err := dc.scaleReplicaSetAndRecordEvent(newRS, *(deployment.Spec.Replicas), deployment)
+		scaled, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, newRS, *(deployment.Spec.Replicas), deployment)
 		return scaled, err
 	}
 	newReplicasCount, err := deploymentutil.NewRSNewReplicas(deployment, allRSs, newRS)
 	if err != nil {
 		return false, err
 	}
-	scaled, _, err := dc.scaleReplicaSetAndRecordEvent(newRS, newReplicasCount, deployment)
+	// In batched rollouts the new replica set only grows by one batch at a time, and only
+	// once the previous batch has become available.
+	if batchSize, ok := deploymentutil.GetRollingBatchSize(deployment); ok {
+		newReplicasCount = deploymentutil.BatchLimitedReplicas(newRS, newReplicasCount, batchSize)
+	}
+	// With the quota preflight enabled, make sure surge pods actually fit the namespace's
+	// ResourceQuota headroom; otherwise fall back to a zero-surge rollout.
+	if deployment.Annotations[deploymentutil.QuotaPreflightAnnotation] == "true" {
+		count, err := dc.quotaLimitedReplicas(ctx, deployment, allRSs, newRS, newReplicasCount)
+		if err != nil {
+			return false, err
+		}
+		newReplicasCount = count
+	}
+	scaled, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, newRS, newReplicasCount, deployment)
 	return scaled, err
 }
 
-func (dc *DeploymentController) reconcileOldReplicaSets(allRSs []*apps.ReplicaSet, oldRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, deployment *apps.Deployment) (bool, error) {
+// quotaLimitedReplicas checks whether growing the new replica set to newReplicasCount
+// would surge above the deployment's desired size without enough pod headroom left in the
+// namespace's ResourceQuotas. If so, it returns a size that keeps the rollout at zero
+// surge and emits an event; otherwise newReplicasCount is returned unchanged. Quota read
+// failures are not treated as errors: the apiserver still enforces quota on pod creation.
+func (dc *DeploymentController) quotaLimitedReplicas(ctx context.Context, deployment *apps.Deployment, allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, newReplicasCount int32) (int32, error) {
+	growth := newReplicasCount - *(newRS.Spec.Replicas)
+	if growth <= 0 {
+		return newReplicasCount, nil
+	}
+	allPodsCount := deploymentutil.GetReplicaCountForReplicaSets(allRSs)
+	if allPodsCount+growth <= *(deployment.Spec.Replicas) {
+		// Not surging above the desired size; quota headroom is the user's problem.
+		return newReplicasCount, nil
+	}
+	quotas, err := dc.client.CoreV1().ResourceQuotas(deployment.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.V(2).Infof("Couldn't list resource quotas in namespace %q for the surge preflight of deployment %q: %v", deployment.Namespace, deployment.Name, err)
+		return newReplicasCount, nil
+	}
+	for _, quota := range quotas.Items {
+		hard, ok := quota.Status.Hard[v1.ResourcePods]
+		if !ok {
+			continue
+		}
+		used := quota.Status.Used[v1.ResourcePods]
+		if headroom := hard.Value() - used.Value(); headroom < int64(growth) {
+			zeroSurgeCount := *(deployment.Spec.Replicas) - (allPodsCount - *(newRS.Spec.Replicas))
+			if zeroSurgeCount < *(newRS.Spec.Replicas) {
+				zeroSurgeCount = *(newRS.Spec.Replicas)
+			}
+			if newReplicasCount > zeroSurgeCount {
+				dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, deploymentutil.SurgeBlockedByQuotaReason,
+					"ResourceQuota %q leaves headroom for only %d pod(s); continuing with a zero-surge rollout", quota.Name, headroom)
+				newReplicasCount = zeroSurgeCount
+			}
+			break
+		}
+	}
+	return newReplicasCount, nil
+}
+
+func (dc *DeploymentController) reconcileOldReplicaSets(ctx context.Context, allRSs []*apps.ReplicaSet, oldRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, deployment *apps.Deployment) (bool, error) {
 	oldPodsCount := deploymentutil.GetReplicaCountForReplicaSets(oldRSs)
 	if oldPodsCount == 0 {
 		// Can't scale down further
@@ -138,7 +241,7 @@ func (dc *DeploymentController) reconcileOldReplicaSets(allRSs []*apps.ReplicaSe
 
 	// Clean up unhealthy replicas first, otherwise unhealthy replicas will block deployment
 	// and cause timeout. See https://github.com/kubernetes/kubernetes/issues/16737
-	oldRSs, cleanupCount, err := dc.cleanupUnhealthyReplicas(oldRSs, deployment, maxScaledDown)
+	oldRSs, cleanupCount, err := dc.cleanupUnhealthyReplicas(ctx, oldRSs, deployment, maxScaledDown)
 	if err != nil {
 		return false, nil
 	}
@@ -146,7 +249,7 @@ func (dc *DeploymentController) reconcileOldReplicaSets(allRSs []*apps.ReplicaSe
 
 	// Scale down old replica sets, need check maxUnavailable to ensure we can scale down
 	allRSs = append(oldRSs, newRS)
-	scaledDownCount, err := dc.scaleDownOldReplicaSetsForRollingUpdate(allRSs, oldRSs, deployment)
+	scaledDownCount, err := dc.scaleDownOldReplicaSetsForRollingUpdate(ctx, allRSs, oldRSs, deployment)
 	if err != nil {
 		return false, nil
 	}
@@ -157,7 +260,7 @@ func (dc *DeploymentController) reconcileOldReplicaSets(allRSs []*apps.ReplicaSe
 }
 
 // cleanupUnhealthyReplicas will scale down old replica sets with unhealthy replicas, so that all unhealthy replicas will be deleted.
-func (dc *DeploymentController) cleanupUnhealthyReplicas(oldRSs []*apps.ReplicaSet, deployment *apps.Deployment, maxCleanupCount int32) ([]*apps.ReplicaSet, int32, error) {
+func (dc *DeploymentController) cleanupUnhealthyReplicas(ctx context.Context, oldRSs []*apps.ReplicaSet, deployment *apps.Deployment, maxCleanupCount int32) ([]*apps.ReplicaSet, int32, error) {
 	sort.Sort(controller.ReplicaSetsByCreationTimestamp(oldRSs))
 	// Safely scale down all old replica sets with unhealthy replicas. Replica set will sort the pods in the order
 	// such that not-ready < ready, unscheduled < scheduled, and pending < running. This ensures that unhealthy replicas will
@@ -182,7 +285,7 @@ func (dc *DeploymentController) cleanupUnhealthyReplicas(oldRSs []*apps.ReplicaS
 		if newReplicasCount > *(targetRS.Spec.Replicas) {
 			return nil, 0, fmt.Errorf("when cleaning up unhealthy replicas, got invalid request to scale down %s/%s %d -> %d", targetRS.Namespace, targetRS.Name, *(targetRS.Spec.Replicas), newReplicasCount)
 		}
-		_, updatedOldRS, err := dc.scaleReplicaSetAndRecordEvent(targetRS, newReplicasCount, deployment)
+		_, updatedOldRS, err := dc.scaleReplicaSetAndRecordEvent(ctx, targetRS, newReplicasCount, deployment)
 		if err != nil {
 			return nil, totalScaledDown, err
 		}
@@ -194,7 +297,7 @@ func (dc *DeploymentController) cleanupUnhealthyReplicas(oldRSs []*apps.ReplicaS
 
 // scaleDownOldReplicaSetsForRollingUpdate scales down old replica sets when deployment strategy is "RollingUpdate".
 // Need check maxUnavailable to ensure availability
-func (dc *DeploymentController) scaleDownOldReplicaSetsForRollingUpdate(allRSs []*apps.ReplicaSet, oldRSs []*apps.ReplicaSet, deployment *apps.Deployment) (int32, error) {
+func (dc *DeploymentController) scaleDownOldReplicaSetsForRollingUpdate(ctx context.Context, allRSs []*apps.ReplicaSet, oldRSs []*apps.ReplicaSet, deployment *apps.Deployment) (int32, error) {
 	maxUnavailable := deploymentutil.MaxUnavailable(*deployment)
 
 	// Check if we can scale down.
@@ -207,10 +310,23 @@ func (dc *DeploymentController) scaleDownOldReplicaSetsForRollingUpdate(allRSs [
 	}
 	klog.V(4).Infof("Found %d available pods in deployment %s, scaling down old RSes", availablePodCount, deployment.Name)
 
-	sort.Sort(controller.ReplicaSetsByCreationTimestamp(oldRSs))
+	dc.sortReplicaSetsForScaleDown(deployment, oldRSs)
 
 	totalScaledDown := int32(0)
 	totalScaleDownCount := availablePodCount - minAvailable
+	// In batched rollouts old pods are removed at most one batch at a time.
+	if batchSize, ok := deploymentutil.GetRollingBatchSize(deployment); ok && totalScaleDownCount > batchSize {
+		totalScaleDownCount = batchSize
+	}
+	// Never let the rollout itself disrupt more pods than the PodDisruptionBudgets
+	// covering this deployment's pods currently allow; maxUnavailable is computed
+	// independently of them.
+	if dc.pdbLister != nil {
+		if allowed, covered := dc.allowedDisruptions(deployment); covered && totalScaleDownCount > allowed {
+			klog.V(4).Infof("Limiting scale down of old RSes of deployment %s/%s to %d by PodDisruptionBudget", deployment.Namespace, deployment.Name, allowed)
+			totalScaleDownCount = allowed
+		}
+	}
 	for _, targetRS := range oldRSs {
 		if totalScaledDown >= totalScaleDownCount {
 			// No further scaling required.
@@ -226,7 +342,7 @@ func (dc *DeploymentController) scaleDownOldReplicaSetsForRollingUpdate(allRSs [
 		if newReplicasCount > *(targetRS.Spec.Replicas) {
 			return 0, fmt.Errorf("when scaling down old RS, got invalid request to scale down %s/%s %d -> %d", targetRS.Namespace, targetRS.Name, *(targetRS.Spec.Replicas), newReplicasCount)
 		}
-		_, _, err := dc.scaleReplicaSetAndRecordEvent(targetRS, newReplicasCount, deployment)
+		_, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, targetRS, newReplicasCount, deployment)
 		if err != nil {
 			return totalScaledDown, err
 		}
@@ -236,3 +352,331 @@ func (dc *DeploymentController) scaleDownOldReplicaSetsForRollingUpdate(allRSs [
 
 	return totalScaledDown, nil
 }
+
+// approvalGateAllowsPromotion enforces the external approval gate: with approval
+// required, old capacity is only removed once the approved-revision annotation names the
+// current new revision. While unapproved, a machine-readable AwaitingApproval condition
+// says exactly which revision needs sign-off.
+func (dc *DeploymentController) approvalGateAllowsPromotion(d *apps.Deployment, newRS *apps.ReplicaSet) bool {
+	clearCondition := func() {
+		if deploymentutil.GetDeploymentCondition(d.Status, deploymentutil.DeploymentAwaitingApproval) != nil {
+			deploymentutil.RemoveDeploymentCondition(&d.Status, deploymentutil.DeploymentAwaitingApproval)
+		}
+	}
+	if d.Annotations[deploymentutil.ApprovalRequiredAnnotation] != "true" || newRS == nil {
+		clearCondition()
+		return true
+	}
+	revision, err := deploymentutil.Revision(newRS)
+	if err != nil || revision == 0 {
+		clearCondition()
+		return true
+	}
+	if approved, err := strconv.ParseInt(d.Annotations[deploymentutil.ApprovedRevisionAnnotation], 10, 64); err == nil && approved == revision {
+		clearCondition()
+		return true
+	}
+	if deploymentutil.GetDeploymentCondition(d.Status, deploymentutil.DeploymentAwaitingApproval) == nil {
+		dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "AwaitingApproval",
+			"Promotion of revision %d is waiting for approval", revision)
+	}
+	condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentAwaitingApproval, v1.ConditionTrue, "ApprovalPending",
+		fmt.Sprintf("Revision %d requires approval before old capacity is removed", revision))
+	deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+	return false
+}
+
+// checkRolloutDeadlock maintains the RolloutDeadlocked condition: set with an
+// explanatory event when maxSurge and maxUnavailable both resolve to zero for the
+// current replica count, removed otherwise. The condition travels with the status sync
+// at the end of the rollout iteration.
+func (dc *DeploymentController) checkRolloutDeadlock(d *apps.Deployment) {
+	current := deploymentutil.GetDeploymentCondition(d.Status, deploymentutil.DeploymentRolloutDeadlocked)
+	if !deploymentutil.RolloutBudgetsResolveToZero(d) {
+		if current != nil {
+			deploymentutil.RemoveDeploymentCondition(&d.Status, deploymentutil.DeploymentRolloutDeadlocked)
+		}
+		return
+	}
+	if current == nil {
+		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, "RolloutDeadlocked",
+			"maxSurge and maxUnavailable both resolve to 0 for %d replicas; the rollout proceeds with an effective maxUnavailable of 1", *(d.Spec.Replicas))
+	}
+	condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentRolloutDeadlocked, v1.ConditionTrue, "ZeroBudgets",
+		"The declared maxSurge and maxUnavailable both resolve to 0 for the current replica count")
+	deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+}
+
+// reconcileDualRevision keeps the new replica set and the latest old replica set at the
+// declared replica split, scaling any other old replica sets down to zero. Scaling events
+// are handled before rolloutRolling runs and preserve the split proportionally, so this
+// only has to converge the two revisions onto their shares of the desired size.
+func (dc *DeploymentController) reconcileDualRevision(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet, newPortion, oldPortion int32) error {
+	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, true)
+	if err != nil {
+		return err
+	}
+	allRSs := append(oldRSs, newRS)
+
+	// The latest old revision is the one kept alongside the new revision.
+	var stableRS *apps.ReplicaSet
+	stableRevision := int64(-1)
+	for _, rs := range oldRSs {
+		v, err := deploymentutil.Revision(rs)
+		if err != nil {
+			continue
+		}
+		if v > stableRevision {
+			stableRevision = v
+			stableRS = rs
+		}
+	}
+
+	newCount := *(d.Spec.Replicas)
+	oldCount := int32(0)
+	if stableRS != nil {
+		// Round the new revision's share to the nearest replica; the old revision gets
+		// the remainder.
+		newCount = (*(d.Spec.Replicas)*newPortion + (newPortion+oldPortion)/2) / (newPortion + oldPortion)
+		oldCount = *(d.Spec.Replicas) - newCount
+	}
+
+	if _, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, newRS, newCount, d); err != nil {
+		return err
+	}
+	for _, rs := range oldRSs {
+		target := int32(0)
+		if stableRS != nil && rs.UID == stableRS.UID {
+			target = oldCount
+		}
+		if _, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, rs, target, d); err != nil {
+			return err
+		}
+	}
+
+	return dc.syncRolloutStatus(ctx, allRSs, newRS, d)
+}
+
+// maxSurgePodRestarts caps how many unready surge pods the controller deletes per replica
+// set before escalating to the configured failure action.
+const maxSurgePodRestarts = 3
+
+// cleanupStuckSurgePods deletes pods of the new replica set that have stayed unready for
+// longer than the deployment's surge pod TTL, so the replica set controller recreates them
+// on a hopefully healthier node. Deletions are capped per replica set; once the cap is
+// reached the controller escalates to the configured failure action instead.
+func (dc *DeploymentController) cleanupStuckSurgePods(ctx context.Context, deployment *apps.Deployment, newRS *apps.ReplicaSet) error {
+	ttl, ok := deploymentutil.GetSurgePodTTL(deployment)
+	if !ok || newRS == nil || dc.podLister == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	pods, err := dc.podLister.Pods(deployment.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+
+	restarts := deploymentutil.GetSurgePodRestarts(newRS)
+	deleted := int32(0)
+	now := dc.now()
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.UID != newRS.UID {
+			continue
+		}
+		if pod.DeletionTimestamp != nil || podutil.IsPodReady(pod) {
+			continue
+		}
+		if age := now.Sub(pod.CreationTimestamp.Time); age < ttl {
+			// Not expired yet; make sure we come back when it would be.
+			dc.enqueueAfter(deployment, ttl-age+time.Second)
+			continue
+		}
+		if restarts+deleted >= maxSurgePodRestarts {
+			dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, "SurgePodRetriesExhausted",
+				"Giving up recreating unready surge pods of replica set %s after %d attempts", newRS.Name, maxSurgePodRestarts)
+			if deployment.Annotations[deploymentutil.SurgePodFailureActionAnnotation] == deploymentutil.SurgePodFailureActionPause && !deployment.Spec.Paused {
+				deployment.Spec.Paused = true
+				deployment.Annotations[deploymentutil.PauseCauseAnnotation] = "SurgePodRetriesExhausted"
+				if _, err := dc.client.AppsV1().Deployments(deployment.Namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+					return err
+				}
+				dc.auditMutation("Deployment", deployment.Namespace, deployment.Name, "update", "paused", "SurgePodRetriesExhausted")
+			}
+			break
+		}
+		if err := dc.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		dc.eventRecorder.Eventf(deployment, v1.EventTypeNormal, "SurgePodDeleted",
+			"Deleted pod %s that stayed unready longer than %v so it gets recreated", pod.Name, ttl)
+		dc.auditMutation("Pod", pod.Namespace, pod.Name, "delete", "", "SurgePodTTLExceeded")
+		deleted++
+	}
+
+	if deleted > 0 {
+		// Record the consumed retries with a merge patch so concurrent changes to the
+		// replica set are neither clobbered nor a source of conflicts.
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+			deploymentutil.SurgePodRestartsAnnotation, strconv.Itoa(int(restarts+deleted))))
+		if _, err := dc.client.AppsV1().ReplicaSets(newRS.Namespace).Patch(ctx, newRS.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reactToCrashLoop rolls back (or pauses) a rollout whose new-revision pods hit the
+// deployment's crash-loop threshold within the configured window after the new replica
+// set was created. It returns true when it took an action; the resulting spec update
+// requeues the deployment, which then performs the rollback or paused sync.
+func (dc *DeploymentController) reactToCrashLoop(ctx context.Context, deployment *apps.Deployment, newRS *apps.ReplicaSet) (bool, error) {
+	threshold, ok := deploymentutil.GetCrashLoopThreshold(deployment)
+	if !ok || newRS == nil || dc.podLister == nil {
+		return false, nil
+	}
+	if dc.now().Sub(newRS.CreationTimestamp.Time) > deploymentutil.GetCrashLoopWindow(deployment) {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	pods, err := dc.podLister.Pods(deployment.Namespace).List(selector)
+	if err != nil {
+		return false, err
+	}
+	crashLooping := int32(0)
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.UID != newRS.UID {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+				crashLooping++
+				break
+			}
+		}
+	}
+	if crashLooping < threshold {
+		return false, nil
+	}
+
+	if deployment.Annotations[deploymentutil.CrashLoopActionAnnotation] == deploymentutil.CrashLoopActionPause {
+		if deployment.Spec.Paused {
+			return false, nil
+		}
+		deployment.Spec.Paused = true
+		deployment.Annotations[deploymentutil.PauseCauseAnnotation] = "CrashLoop"
+		if _, err := dc.client.AppsV1().Deployments(deployment.Namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			return false, err
+		}
+		dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, "CrashLoopPause",
+			"Paused deployment %q: %d new pod(s) are crash looping", deployment.Name, crashLooping)
+		dc.auditMutation("Deployment", deployment.Namespace, deployment.Name, "update", "paused", "CrashLoopPause")
+		return true, nil
+	}
+
+	// Request a rollback to the previous revision through the regular rollback path.
+	setRollbackTo(deployment, &extensions.RollbackConfig{Revision: 0})
+	deployment.Annotations[deploymentutil.RollbackTriggerAnnotation] = "CrashLoop"
+	if _, err := dc.client.AppsV1().Deployments(deployment.Namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	dc.eventRecorder.Eventf(deployment, v1.EventTypeWarning, "CrashLoopRollback",
+		"Rolling back deployment %q: %d new pod(s) are crash looping", deployment.Name, crashLooping)
+	dc.auditMutation("Deployment", deployment.Namespace, deployment.Name, "update", "requested rollback", "CrashLoopRollback")
+	return true, nil
+}
+
+// allowedDisruptions returns the smallest number of currently allowed disruptions among
+// the PodDisruptionBudgets covering the deployment's pods, and whether any budget covers
+// them at all.
+func (dc *DeploymentController) allowedDisruptions(deployment *apps.Deployment) (int32, bool) {
+	pdbs, err := dc.pdbLister.PodDisruptionBudgets(deployment.Namespace).List(labels.Everything())
+	if err != nil || len(pdbs) == 0 {
+		return 0, false
+	}
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+	allowed := int32(math.MaxInt32)
+	covered := false
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(podLabels) {
+			continue
+		}
+		covered = true
+		if pdb.Status.DisruptionsAllowed < allowed {
+			allowed = pdb.Status.DisruptionsAllowed
+		}
+	}
+	return allowed, covered
+}
+
+// sortReplicaSetsForScaleDown sorts the old replica sets in the order in which they should be
+// scaled down. The base ordering is controlled by the deployment's scale-down-order
+// annotation and defaults to oldest-first. When the PodDeletionCost feature is enabled,
+// replica sets whose pods carry a lower aggregate pod-deletion-cost are additionally moved
+// to the front so that cheap pods are removed before expensive ones; replica sets with
+// equal cost keep the base ordering.
+func (dc *DeploymentController) sortReplicaSetsForScaleDown(deployment *apps.Deployment, oldRSs []*apps.ReplicaSet) {
+	switch deploymentutil.GetScaleDownOrder(deployment) {
+	case deploymentutil.ScaleDownNewestFirst:
+		sort.Sort(sort.Reverse(controller.ReplicaSetsByCreationTimestamp(oldRSs)))
+	case deploymentutil.ScaleDownLeastReadyFirst:
+		sort.Sort(deploymentutil.ReplicaSetsByReadyReplicas(oldRSs))
+	default:
+		sort.Sort(controller.ReplicaSetsByCreationTimestamp(oldRSs))
+	}
+	if !utilfeature.DefaultFeatureGate.Enabled(features.PodDeletionCost) {
+		return
+	}
+	costs := dc.replicaSetDeletionCosts(deployment, oldRSs)
+	if len(costs) == 0 {
+		return
+	}
+	sort.SliceStable(oldRSs, func(i, j int) bool {
+		return costs[oldRSs[i].UID] < costs[oldRSs[j].UID]
+	})
+}
+
+// replicaSetDeletionCosts returns the sum of the pod-deletion-cost annotations of the pods
+// owned by each of the given replica sets, keyed by replica set UID. Pods without the
+// annotation count as cost 0. An empty map is returned when the costs cannot be computed,
+// in which case callers should fall back to the default scale-down ordering.
+func (dc *DeploymentController) replicaSetDeletionCosts(deployment *apps.Deployment, oldRSs []*apps.ReplicaSet) map[types.UID]int64 {
+	if dc.podLister == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	pods, err := dc.podLister.Pods(deployment.Namespace).List(selector)
+	if err != nil {
+		klog.V(4).Infof("Couldn't list pods for deployment %s/%s to compute deletion costs: %v", deployment.Namespace, deployment.Name, err)
+		return nil
+	}
+	costs := make(map[types.UID]int64, len(oldRSs))
+	for _, rs := range oldRSs {
+		costs[rs.UID] = 0
+	}
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil {
+			continue
+		}
+		// Only count pods owned by one of the old replica sets.
+		if _, ok := costs[controllerRef.UID]; !ok {
+			continue
+		}
+		cost, _ := helper.GetDeletionCostFromPodAnnotations(pod.Annotations)
+		costs[controllerRef.UID] += int64(cost)
+	}
+	return costs
+}
@@ -17,13 +17,21 @@ limitations under the License.
 package deployment
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	api "k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/controller"
 )
 
 func TestDeploymentController_reconcileNewReplicaSet(t *testing.T) {
@@ -90,7 +98,7 @@ func TestDeploymentController_reconcileNewReplicaSet(t *testing.T) {
 			client:        &fake,
 			eventRecorder: &record.FakeRecorder{},
 		}
-		scaled, err := controller.reconcileNewReplicaSet(allRSs, newRS, deployment)
+		scaled, err := controller.reconcileNewReplicaSet(context.TODO(), allRSs, newRS, deployment)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			continue
@@ -109,8 +117,12 @@ func TestDeploymentController_reconcileNewReplicaSet(t *testing.T) {
 			t.Errorf("expected 1 action during scale, got: %v", fake.Actions())
 			continue
 		}
-		updated := fake.Actions()[0].(core.UpdateAction).GetObject().(*apps.ReplicaSet)
-		if e, a := test.expectedNewReplicas, int(*(updated.Spec.Replicas)); e != a {
+		updatedReplicas, ok := replicasFromRSPatchAction(t, fake.Actions()[0])
+		if !ok {
+			t.Errorf("expected a scale patch action, got: %v", fake.Actions()[0])
+			continue
+		}
+		if e, a := test.expectedNewReplicas, int(updatedReplicas); e != a {
 			t.Errorf("expected update to %d replicas, got %d", e, a)
 		}
 	}
@@ -197,7 +209,7 @@ func TestDeploymentController_reconcileOldReplicaSets(t *testing.T) {
 			eventRecorder: &record.FakeRecorder{},
 		}
 
-		scaled, err := controller.reconcileOldReplicaSets(allRSs, oldRSs, newRS, deployment)
+		scaled, err := controller.reconcileOldReplicaSets(context.TODO(), allRSs, oldRSs, newRS, deployment)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			continue
@@ -265,7 +277,7 @@ func TestDeploymentController_cleanupUnhealthyReplicas(t *testing.T) {
 			client:        &fakeClientset,
 			eventRecorder: &record.FakeRecorder{},
 		}
-		_, cleanupCount, err := controller.cleanupUnhealthyReplicas(oldRSs, deployment, int32(test.maxCleanupCount))
+		_, cleanupCount, err := controller.cleanupUnhealthyReplicas(context.TODO(), oldRSs, deployment, int32(test.maxCleanupCount))
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			continue
@@ -339,7 +351,7 @@ func TestDeploymentController_scaleDownOldReplicaSetsForRollingUpdate(t *testing
 			client:        &fakeClientset,
 			eventRecorder: &record.FakeRecorder{},
 		}
-		scaled, err := controller.scaleDownOldReplicaSetsForRollingUpdate(allRSs, oldRSs, deployment)
+		scaled, err := controller.scaleDownOldReplicaSetsForRollingUpdate(context.TODO(), allRSs, oldRSs, deployment)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			continue
@@ -354,26 +366,98 @@ func TestDeploymentController_scaleDownOldReplicaSetsForRollingUpdate(t *testing
 			t.Errorf("expected scaling to occur; actions: %v", fakeClientset.Actions())
 			continue
 		}
-		// There are both list and update actions logged, so extract the update
+		// There are both list and scale patch actions logged, so extract the patch
 		// action for verification.
-		var updateAction core.UpdateAction
+		var scaleAction core.Action
 		for _, action := range fakeClientset.Actions() {
-			switch a := action.(type) {
-			case core.UpdateAction:
-				if updateAction != nil {
-					t.Errorf("expected only 1 update action; had %v and found %v", updateAction, a)
-				} else {
-					updateAction = a
-				}
+			if _, ok := action.(core.PatchAction); !ok {
+				continue
+			}
+			if scaleAction != nil {
+				t.Errorf("expected only 1 scale patch action; had %v and found %v", scaleAction, action)
+			} else {
+				scaleAction = action
 			}
 		}
-		if updateAction == nil {
-			t.Errorf("expected an update action")
+		if scaleAction == nil {
+			t.Errorf("expected a scale patch action")
 			continue
 		}
-		updated := updateAction.GetObject().(*apps.ReplicaSet)
-		if e, a := test.expectedOldReplicas, int(*(updated.Spec.Replicas)); e != a {
+		updatedReplicas, ok := replicasFromRSPatchAction(t, scaleAction)
+		if !ok {
+			t.Errorf("expected a scale patch action, got: %v", scaleAction)
+			continue
+		}
+		if e, a := test.expectedOldReplicas, int(updatedReplicas); e != a {
 			t.Errorf("expected update to %d replicas, got %d", e, a)
 		}
 	}
 }
+
+func TestDeploymentController_sortReplicaSetsForScaleDown(t *testing.T) {
+	selector := map[string]string{"foo": "bar"}
+	d := newDeployment("foo", 10, nil, nil, nil, selector)
+	cheapRS := newReplicaSet(d, "foo-cheap", 2)
+	expensiveRS := newReplicaSet(d, "foo-expensive", 2)
+	// The expensive replica set is older, so without deletion costs it would be
+	// scaled down first.
+	expensiveRS.CreationTimestamp = metav1.Time{Time: time.Unix(10, 0)}
+	cheapRS.CreationTimestamp = metav1.Time{Time: time.Unix(20, 0)}
+
+	cheapPod := generatePodFromRS(cheapRS)
+	cheapPod.Annotations = map[string]string{api.PodDeletionCost: "-100"}
+	expensivePod := generatePodFromRS(expensiveRS)
+	expensivePod.Annotations = map[string]string{api.PodDeletionCost: "100"}
+
+	informers := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	for _, pod := range []*v1.Pod{cheapPod, expensivePod} {
+		informers.Core().V1().Pods().Informer().GetIndexer().Add(pod)
+	}
+	dc := &DeploymentController{
+		podLister: informers.Core().V1().Pods().Lister(),
+	}
+
+	oldRSs := []*apps.ReplicaSet{cheapRS, expensiveRS}
+	dc.sortReplicaSetsForScaleDown(d, oldRSs)
+	if oldRSs[0].Name != cheapRS.Name {
+		t.Errorf("expected replica set %q with the lowest deletion cost to be scaled down first, got %q", cheapRS.Name, oldRSs[0].Name)
+	}
+}
+
+func TestScaleDownOldReplicaSetsLimitedByPodDisruptionBudget(t *testing.T) {
+	selector := map[string]string{"foo": "bar"}
+	maxSurge := intstr.FromInt(0)
+	maxUnavailable := intstr.FromInt(2)
+	d := newDeployment("foo", 10, nil, &maxSurge, &maxUnavailable, selector)
+
+	newRS := rs("foo-new", 0, selector, noTimestamp)
+	oldRS := rs("foo-old", 10, selector, noTimestamp)
+	oldRS.Status.AvailableReplicas = 10
+	allRSs := []*apps.ReplicaSet{oldRS, newRS}
+	oldRSs := []*apps.ReplicaSet{oldRS}
+
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-pdb", Namespace: d.Namespace},
+		Spec: policy.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+		},
+		Status: policy.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}
+	informers := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	informers.Policy().V1().PodDisruptionBudgets().Informer().GetIndexer().Add(pdb)
+
+	dc := &DeploymentController{
+		client:        &fake.Clientset{},
+		eventRecorder: &record.FakeRecorder{},
+		pdbLister:     informers.Policy().V1().PodDisruptionBudgets().Lister(),
+	}
+
+	scaledDown, err := dc.scaleDownOldReplicaSetsForRollingUpdate(context.TODO(), allRSs, oldRSs, d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// maxUnavailable would allow scaling down by 2, but the budget only allows 1.
+	if scaledDown != 1 {
+		t.Errorf("expected scale down to be limited to 1 by the PodDisruptionBudget, got %d", scaledDown)
+	}
+}
@@ -19,14 +19,18 @@ package deployment
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/controller"
 	deploymentutil "k8s.io/kubernetes/pkg/controller/deployment/util"
@@ -34,25 +38,25 @@ import (
 )
 
 // syncStatusOnly only updates Deployments Status and doesn't take any mutating actions.
-func (dc *DeploymentController) syncStatusOnly(d *apps.Deployment, rsList []*apps.ReplicaSet) error {
-	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(d, rsList, false)
+func (dc *DeploymentController) syncStatusOnly(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, false)
 	if err != nil {
 		return err
 	}
 
 	allRSs := append(oldRSs, newRS)
-	return dc.syncDeploymentStatus(allRSs, newRS, d)
+	return dc.syncDeploymentStatus(ctx, allRSs, newRS, d)
 }
 
 // sync is responsible for reconciling deployments on scaling events or when they
 // are paused.
-func (dc *DeploymentController) sync(d *apps.Deployment, rsList []*apps.ReplicaSet) error {
-	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(d, rsList, false)
+func (dc *DeploymentController) sync(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) error {
+	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, false)
 	if err != nil {
 		return err
 	}
 	// 스케일업 하기
-	if err := dc.scale(d, newRS, oldRSs); err != nil {
+	if err := dc.scale(ctx, d, newRS, oldRSs); err != nil {
 		// If we get an error while trying to scale, the deployment will be requeued
 		// so we can abort this resync
 		return err
@@ -60,47 +64,110 @@ func (dc *DeploymentController) sync(d *apps.Deployment, rsList []*apps.ReplicaS
 
 	// Clean up the deployment when it's paused and no rollback is in flight.
 	if d.Spec.Paused && getRollbackTo(d) == nil {
-		if err := dc.cleanupDeployment(oldRSs, d); err != nil {
-			return err
-		}
+		dc.enqueueCleanup(d)
 	}
 
 	allRSs := append(oldRSs, newRS)
-	return dc.syncDeploymentStatus(allRSs, newRS, d)
+	return dc.syncDeploymentStatus(ctx, allRSs, newRS, d)
 }
 
 // checkPausedConditions checks if the given deployment is paused or not and adds an appropriate condition.
 // These conditions are needed so that we won't accidentally report lack of progress for resumed deployments
 // that were paused for longer than progressDeadlineSeconds.
-func (dc *DeploymentController) checkPausedConditions(d *apps.Deployment) error {
-	if !deploymentutil.HasProgressDeadline(d) {
-		return nil
-	}
-	cond := deploymentutil.GetDeploymentCondition(d.Status, apps.DeploymentProgressing)
-	if cond != nil && cond.Reason == deploymentutil.TimedOutReason {
-		// If we have reported lack of progress, do not overwrite it with a paused condition.
-		return nil
-	}
-	pausedCondExists := cond != nil && cond.Reason == deploymentutil.PausedDeployReason
-
+func (dc *DeploymentController) checkPausedConditions(ctx context.Context, d *apps.Deployment) error {
+	// Maintain the dedicated RolloutPaused condition independently of the progress
+	// deadline: present exactly while the deployment is paused, with lastTransitionTime
+	// marking when the pause started.
 	needsUpdate := false
-	if d.Spec.Paused && !pausedCondExists {
-		condition := deploymentutil.NewDeploymentCondition(apps.DeploymentProgressing, v1.ConditionUnknown, deploymentutil.PausedDeployReason, "Deployment is paused")
+	rolloutPaused := deploymentutil.GetDeploymentCondition(d.Status, deploymentutil.DeploymentRolloutPaused)
+	// Controller-initiated pauses record their cause in an annotation, which becomes
+	// the condition reason; everything else is a user pause.
+	pauseReason := deploymentutil.UserPausedReason
+	if cause := d.Annotations[deploymentutil.PauseCauseAnnotation]; cause != "" {
+		pauseReason = cause
+	}
+	switch {
+	case d.Spec.Paused && (rolloutPaused == nil || rolloutPaused.Status != v1.ConditionTrue || rolloutPaused.Reason != pauseReason):
+		condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentRolloutPaused, v1.ConditionTrue, pauseReason, "Deployment is paused")
 		deploymentutil.SetDeploymentCondition(&d.Status, *condition)
 		needsUpdate = true
-	} else if !d.Spec.Paused && pausedCondExists {
-		condition := deploymentutil.NewDeploymentCondition(apps.DeploymentProgressing, v1.ConditionUnknown, deploymentutil.ResumedDeployReason, "Deployment is resumed")
-		deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+	case !d.Spec.Paused && rolloutPaused != nil:
+		deploymentutil.RemoveDeploymentCondition(&d.Status, deploymentutil.DeploymentRolloutPaused)
 		needsUpdate = true
 	}
+	// A stale pause cause must not leak into the next pause. The clearing patch bumps
+	// the resourceVersion, so it runs after the status write below; issuing it first
+	// would turn every resume of a controller-paused deployment into a conflict.
+	clearPauseCause := false
+	if !d.Spec.Paused {
+		_, clearPauseCause = d.Annotations[deploymentutil.PauseCauseAnnotation]
+	}
 
-	if !needsUpdate {
-		return nil
+	if deploymentutil.HasProgressDeadline(d) {
+		cond := deploymentutil.GetDeploymentCondition(d.Status, apps.DeploymentProgressing)
+		if cond != nil && cond.Reason == deploymentutil.TimedOutReason {
+			// If we have reported lack of progress, do not overwrite it with a paused condition.
+		} else {
+			pausedCondExists := cond != nil && cond.Reason == deploymentutil.PausedDeployReason
+			if d.Spec.Paused && !pausedCondExists {
+				condition := deploymentutil.NewDeploymentCondition(apps.DeploymentProgressing, v1.ConditionUnknown, deploymentutil.PausedDeployReason, "Deployment is paused")
+				deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+				needsUpdate = true
+			} else if !d.Spec.Paused && pausedCondExists {
+				condition := deploymentutil.NewDeploymentCondition(apps.DeploymentProgressing, v1.ConditionUnknown, deploymentutil.ResumedDeployReason, "Deployment is resumed")
+				deploymentutil.SetDeploymentCondition(&d.Status, *condition)
+				needsUpdate = true
+			}
+		}
 	}
 
-	var err error
-	_, err = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{})
-	return err
+	if needsUpdate {
+		if _, err := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if clearPauseCause {
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, deploymentutil.PauseCauseAnnotation))
+		if _, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			klog.V(2).Infof("Couldn't clear the pause cause of deployment %s/%s: %v", d.Namespace, d.Name, err)
+		}
+		delete(d.Annotations, deploymentutil.PauseCauseAnnotation)
+	}
+	return nil
+}
+
+// autoPromoteIfBaked resumes a paused deployment once it has stayed paused and healthy for
+// the bake duration declared in its annotations. Healthy means the controller acknowledged
+// the pause (paused Progressing condition) and the deployment has minimum availability.
+// When the bake time has not elapsed yet the deployment is requeued for the remainder.
+func (dc *DeploymentController) autoPromoteIfBaked(ctx context.Context, d *apps.Deployment) (bool, error) {
+	bake, ok := deploymentutil.GetAutoPromoteAfter(d)
+	if !ok {
+		return false, nil
+	}
+	cond := deploymentutil.GetDeploymentCondition(d.Status, apps.DeploymentProgressing)
+	if cond == nil || cond.Reason != deploymentutil.PausedDeployReason {
+		return false, nil
+	}
+	if available := deploymentutil.GetDeploymentCondition(d.Status, apps.DeploymentAvailable); available == nil || available.Status != v1.ConditionTrue {
+		// Not healthy; leave the deployment paused. Availability changes enqueue it again.
+		return false, nil
+	}
+	pausedFor := dc.now().Sub(cond.LastUpdateTime.Time)
+	if pausedFor < bake {
+		dc.enqueueAfter(d, bake-pausedFor+time.Second)
+		return false, nil
+	}
+
+	d.Spec.Paused = false
+	delete(d.Annotations, deploymentutil.PauseCauseAnnotation)
+	if _, err := dc.client.AppsV1().Deployments(d.Namespace).Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "AutoPromoted", "Resumed deployment %q after staying healthy for the %v bake time", d.Name, bake)
+	dc.auditMutation("Deployment", d.Namespace, d.Name, "update", "resumed", "AutoPromoted")
+	return true, nil
 }
 
 // getAllReplicaSetsAndSyncRevision returns all the replica sets for the provided deployment (new and all old), with new RS's and deployment's revision updated.
@@ -114,11 +181,11 @@ func (dc *DeploymentController) checkPausedConditions(d *apps.Deployment) error
 //
 // Note that currently the deployment controller is using caches to avoid querying the server for reads.
 // This may lead to stale reads of replica sets, thus incorrect deployment status.
-func (dc *DeploymentController) getAllReplicaSetsAndSyncRevision(d *apps.Deployment, rsList []*apps.ReplicaSet, createIfNotExisted bool) (*apps.ReplicaSet, []*apps.ReplicaSet, error) {
+func (dc *DeploymentController) getAllReplicaSetsAndSyncRevision(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet, createIfNotExisted bool) (*apps.ReplicaSet, []*apps.ReplicaSet, error) {
 	_, allOldRSs := deploymentutil.FindOldReplicaSets(d, rsList)
 
 	// Get new replica set with the updated revision number
-	newRS, err := dc.getNewReplicaSet(d, rsList, allOldRSs, createIfNotExisted)
+	newRS, err := dc.getNewReplicaSet(ctx, d, rsList, allOldRSs, createIfNotExisted)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -129,6 +196,10 @@ func (dc *DeploymentController) getAllReplicaSetsAndSyncRevision(d *apps.Deploym
 const (
 	// limit revision history length to 100 element (~2000 chars)
 	maxRevHistoryLengthInChars = 2000
+
+	// maxParallelRSScales bounds how many replica sets are scaled concurrently within a
+	// single sync.
+	maxParallelRSScales = 5
 )
 
 // Returns a replica set that matches the intent of the given deployment. Returns nil if the new replica set doesn't exist yet.
@@ -136,7 +207,7 @@ const (
 // 2. If there's existing new RS, update its revision number if it's smaller than (maxOldRevision + 1), where maxOldRevision is the max revision number among all old RSes.
 // 3. If there's no existing new RS and createIfNotExisted is true, create one with appropriate revision number (maxOldRevision + 1) and replicas.
 // Note that the pod-template-hash will be added to adopted RSes and pods.
-func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, oldRSs []*apps.ReplicaSet, createIfNotExisted bool) (*apps.ReplicaSet, error) {
+func (dc *DeploymentController) getNewReplicaSet(ctx context.Context, d *apps.Deployment, rsList, oldRSs []*apps.ReplicaSet, createIfNotExisted bool) (*apps.ReplicaSet, error) {
 	existingNewRS := deploymentutil.FindNewReplicaSet(d, rsList)
 
 	// Calculate the max revision number among all old RSes
@@ -153,10 +224,12 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 
 		// Set existing new replica set's annotation
 		annotationsUpdated := deploymentutil.SetNewReplicaSetAnnotations(d, rsCopy, newRevision, true, maxRevHistoryLengthInChars)
+		// Apply metadata-only template changes in place instead of rolling out.
+		inPlaceMetadataUpdated := deploymentutil.SyncInPlaceMetadata(d, rsCopy)
 		minReadySecondsNeedsUpdate := rsCopy.Spec.MinReadySeconds != d.Spec.MinReadySeconds
-		if annotationsUpdated || minReadySecondsNeedsUpdate {
+		if annotationsUpdated || inPlaceMetadataUpdated || minReadySecondsNeedsUpdate {
 			rsCopy.Spec.MinReadySeconds = d.Spec.MinReadySeconds
-			return dc.client.AppsV1().ReplicaSets(rsCopy.ObjectMeta.Namespace).Update(context.TODO(), rsCopy, metav1.UpdateOptions{})
+			return dc.client.AppsV1().ReplicaSets(rsCopy.ObjectMeta.Namespace).Update(ctx, rsCopy, metav1.UpdateOptions{})
 		}
 
 		// Should use the revision in existingNewRS's annotation, since it set by before
@@ -174,7 +247,7 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 
 		if needsUpdate {
 			var err error
-			if _, err = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{}); err != nil {
+			if _, err = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{}); err != nil {
 				return nil, err
 			}
 		}
@@ -192,11 +265,22 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 	// Add podTemplateHash label to selector.
 	newRSSelector := labelsutil.CloneSelectorAndAddLabel(d.Spec.Selector, apps.DefaultDeploymentUniqueLabelKey, podTemplateSpecHash)
 
+	// Optionally spread the new revision away from nodes that still run old-revision pods.
+	if d.Annotations[deploymentutil.RevisionSpreadAnnotation] == "true" {
+		var oldHashes []string
+		for _, rs := range oldRSs {
+			if hash := rs.Labels[apps.DefaultDeploymentUniqueLabelKey]; hash != "" {
+				oldHashes = append(oldHashes, hash)
+			}
+		}
+		deploymentutil.InjectRevisionSpreadAffinity(&newRSTemplate, oldHashes)
+	}
+
 	// Create new ReplicaSet
 	newRS := apps.ReplicaSet{
 		ObjectMeta: metav1.ObjectMeta{
 			// Make the name deterministic, to ensure idempotence
-			Name:            d.Name + "-" + podTemplateSpecHash,
+			Name:            deploymentutil.NewRSName(d, newRevision, podTemplateSpecHash),
 			Namespace:       d.Namespace,
 			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(d, controllerKind)},
 			Labels:          newRSTemplate.Labels,
@@ -221,7 +305,7 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 	// hash collisions. If there is any other error, we need to report it in the status of
 	// the Deployment.
 	alreadyExists := false
-	createdRS, err := dc.client.AppsV1().ReplicaSets(d.Namespace).Create(context.TODO(), &newRS, metav1.CreateOptions{})
+	createdRS, err := dc.client.AppsV1().ReplicaSets(d.Namespace).Create(ctx, &newRS, metav1.CreateOptions{})
 	switch {
 	// We may end up hitting this due to a slow cache or a fast resync of the Deployment.
 	case errors.IsAlreadyExists(err):
@@ -237,8 +321,11 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 		// deep equal to the PodTemplateSpec of the Deployment, it's the Deployment's new ReplicaSet.
 		// Otherwise, this is a hash collision and we need to increment the collisionCount field in
 		// the status of the Deployment and requeue to try the creation in the next sync.
+		// The comparison ignores in-place metadata keys just like new-RS matching does, so
+		// replica sets differing only in such metadata are deduped by reuse instead of
+		// bumping the collision count and creating yet another replica set.
 		controllerRef := metav1.GetControllerOf(rs)
-		if controllerRef != nil && controllerRef.UID == d.UID && deploymentutil.EqualIgnoreHash(&d.Spec.Template, &rs.Spec.Template) {
+		if controllerRef != nil && controllerRef.UID == d.UID && deploymentutil.EqualIgnoreHashAndMetadataKeys(&d.Spec.Template, &rs.Spec.Template, deploymentutil.GetInPlaceMetadataKeys(d)) {
 			createdRS = rs
 			err = nil
 			break
@@ -253,7 +340,7 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 		*d.Status.CollisionCount++
 		// Update the collisionCount for the Deployment and let it requeue by returning the original
 		// error.
-		_, dErr := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{})
+		_, dErr := dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
 		if dErr == nil {
 			klog.V(2).Infof("Found a hash collision for deployment %q - bumping collisionCount (%d->%d) to resolve it", d.Name, preCollisionCount, *d.Status.CollisionCount)
 		}
@@ -269,11 +356,30 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 			// We don't really care about this error at this point, since we have a bigger issue to report.
 			// TODO: Identify which errors are permanent and switch DeploymentIsFailed to take into account
 			// these reasons as well. Related issue: https://github.com/kubernetes/kubernetes/issues/18568
-			_, _ = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{})
+			_, _ = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
 		}
 		dc.eventRecorder.Eventf(d, v1.EventTypeWarning, deploymentutil.FailedRSCreateReason, msg)
 		return nil, err
 	}
+	if !alreadyExists {
+		if dc.rsExpectations != nil {
+			dc.rsExpectations.expect(createdRS.UID)
+		}
+		dc.auditMutation("ReplicaSet", createdRS.Namespace, createdRS.Name, "create", fmt.Sprintf("replicas %d", newReplicasCount), deploymentutil.NewReplicaSetReason)
+		// Optionally mirror the new revision into a ControllerRevision so the rollback
+		// target survives replica set cleanup.
+		if err := dc.saveControllerRevision(ctx, d, newRevision); err != nil {
+			return nil, err
+		}
+		// A new revision's rollout starts now; stamp it and clear the completion of the
+		// previous one.
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:null}}}`,
+			deploymentutil.RolloutStartedAtAnnotation, dc.now().UTC().Format(time.RFC3339),
+			deploymentutil.RolloutCompletedAtAnnotation))
+		if _, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			klog.V(2).Infof("Couldn't stamp rollout start for deployment %s/%s: %v", d.Namespace, d.Name, err)
+		}
+	}
 	if !alreadyExists && newReplicasCount > 0 {
 		dc.eventRecorder.Eventf(d, v1.EventTypeNormal, "ScalingReplicaSet", "Scaled up replica set %s to %d", createdRS.Name, newReplicasCount)
 	}
@@ -286,7 +392,7 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 		needsUpdate = true
 	}
 	if needsUpdate {
-		_, err = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(context.TODO(), d, metav1.UpdateOptions{})
+		_, err = dc.client.AppsV1().Deployments(d.Namespace).UpdateStatus(ctx, d, metav1.UpdateOptions{})
 	}
 	return createdRS, err
 }
@@ -296,7 +402,7 @@ func (dc *DeploymentController) getNewReplicaSet(d *apps.Deployment, rsList, old
 // have the effect of hastening the rollout progress, which could produce a higher proportion of unavailable
 // replicas in the event of a problem with the rolled out template. Should run only on scaling events or
 // when a deployment is paused and not during the normal rollout process.
-func (dc *DeploymentController) scale(deployment *apps.Deployment, newRS *apps.ReplicaSet, oldRSs []*apps.ReplicaSet) error {
+func (dc *DeploymentController) scale(ctx context.Context, deployment *apps.Deployment, newRS *apps.ReplicaSet, oldRSs []*apps.ReplicaSet) error {
 	// If there is only one active replica set then we should scale that up to the full count of the
 	// deployment. If there is no active replica set, then we should scale up the newest replica set.
 	// 여기서 새로운 레플리카셋 만드는듯
@@ -304,7 +410,7 @@ func (dc *DeploymentController) scale(deployment *apps.Deployment, newRS *apps.R
 		if *(activeOrLatest.Spec.Replicas) == *(deployment.Spec.Replicas) {
 			return nil
 		}
-		_, _, err := dc.scaleReplicaSetAndRecordEvent(activeOrLatest, *(deployment.Spec.Replicas), deployment)
+		_, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, activeOrLatest, *(deployment.Spec.Replicas), deployment)
 		return err
 	}
 
@@ -312,7 +418,7 @@ func (dc *DeploymentController) scale(deployment *apps.Deployment, newRS *apps.R
 	// This case handles replica set adoption during a saturated new replica set.
 	if deploymentutil.IsSaturated(deployment, newRS) {
 		for _, old := range controller.FilterActiveReplicaSets(oldRSs) {
-			if _, _, err := dc.scaleReplicaSetAndRecordEvent(old, 0, deployment); err != nil {
+			if _, _, err := dc.scaleReplicaSetAndRecordEvent(ctx, old, 0, deployment); err != nil {
 				return err
 			}
 		}
@@ -372,22 +478,28 @@ func (dc *DeploymentController) scale(deployment *apps.Deployment, newRS *apps.R
 			}
 		}
 
-		// Update all replica sets
-		for i := range allRSs {
-			rs := allRSs[i]
-
-			// Add/remove any leftovers to the largest replica set.
-			if i == 0 && deploymentReplicasToAdd != 0 {
-				leftover := deploymentReplicasToAdd - deploymentReplicasAdded
-				nameToSize[rs.Name] = nameToSize[rs.Name] + leftover
-				if nameToSize[rs.Name] < 0 {
-					nameToSize[rs.Name] = 0
-				}
+		// Add/remove any leftovers to the largest replica set.
+		if len(allRSs) > 0 && deploymentReplicasToAdd != 0 {
+			leftover := deploymentReplicasToAdd - deploymentReplicasAdded
+			nameToSize[allRSs[0].Name] = nameToSize[allRSs[0].Name] + leftover
+			if nameToSize[allRSs[0].Name] < 0 {
+				nameToSize[allRSs[0].Name] = 0
 			}
+		}
 
-			// TODO: Use transactions when we have them.
-			if _, _, err := dc.scaleReplicaSet(rs, nameToSize[rs.Name], deployment, scalingOperation); err != nil {
-				// Return as soon as we fail, the deployment is requeued
+		// Update all replica sets, with bounded parallelism: a deployment with many
+		// retained replica sets shouldn't pay one API round trip after another.
+		// TODO: Use transactions when we have them.
+		scaleErrs := make([]error, len(allRSs))
+		workqueue.ParallelizeUntil(ctx, maxParallelRSScales, len(allRSs), func(i int) {
+			rs := allRSs[i]
+			if _, _, err := dc.scaleReplicaSet(ctx, rs, nameToSize[rs.Name], deployment, scalingOperation); err != nil {
+				scaleErrs[i] = err
+			}
+		})
+		for _, err := range scaleErrs {
+			if err != nil {
+				// Fail the sync on the first error, the deployment is requeued
 				return err
 			}
 		}
@@ -395,7 +507,7 @@ func (dc *DeploymentController) scale(deployment *apps.Deployment, newRS *apps.R
 	return nil
 }
 
-func (dc *DeploymentController) scaleReplicaSetAndRecordEvent(rs *apps.ReplicaSet, newScale int32, deployment *apps.Deployment) (bool, *apps.ReplicaSet, error) {
+func (dc *DeploymentController) scaleReplicaSetAndRecordEvent(ctx context.Context, rs *apps.ReplicaSet, newScale int32, deployment *apps.Deployment) (bool, *apps.ReplicaSet, error) {
 	// No need to scale
 	if *(rs.Spec.Replicas) == newScale {
 		return false, rs, nil
@@ -406,12 +518,13 @@ func (dc *DeploymentController) scaleReplicaSetAndRecordEvent(rs *apps.ReplicaSe
 	} else {
 		scalingOperation = "down"
 	}
-	scaled, newRS, err := dc.scaleReplicaSet(rs, newScale, deployment, scalingOperation)
+	scaled, newRS, err := dc.scaleReplicaSet(ctx, rs, newScale, deployment, scalingOperation)
 	return scaled, newRS, err
 }
 
-func (dc *DeploymentController) scaleReplicaSet(rs *apps.ReplicaSet, newScale int32, deployment *apps.Deployment, scalingOperation string) (bool, *apps.ReplicaSet, error) {
+func (dc *DeploymentController) scaleReplicaSet(ctx context.Context, rs *apps.ReplicaSet, newScale int32, deployment *apps.Deployment, scalingOperation string) (bool, *apps.ReplicaSet, error) {
 
+	oldScale := *(rs.Spec.Replicas)
 	sizeNeedsUpdate := *(rs.Spec.Replicas) != newScale
 
 	annotationsNeedUpdate := deploymentutil.ReplicasAnnotationsNeedUpdate(rs, *(deployment.Spec.Replicas), *(deployment.Spec.Replicas)+deploymentutil.MaxSurge(*deployment))
@@ -422,11 +535,25 @@ func (dc *DeploymentController) scaleReplicaSet(rs *apps.ReplicaSet, newScale in
 		rsCopy := rs.DeepCopy()
 		*(rsCopy.Spec.Replicas) = newScale
 		deploymentutil.SetReplicasAnnotations(rsCopy, *(deployment.Spec.Replicas), *(deployment.Spec.Replicas)+deploymentutil.MaxSurge(*deployment))
+		// Scale through a strategic merge patch carrying only the replica count and the
+		// replica annotations, so concurrent metadata changes on the replica set are
+		// neither clobbered nor a source of conflict retries.
 		// etcd 업데이트!!!
-		rs, err = dc.client.AppsV1().ReplicaSets(rsCopy.Namespace).Update(context.TODO(), rsCopy, metav1.UpdateOptions{})
-		if err == nil && sizeNeedsUpdate {
-			scaled = true
-			dc.eventRecorder.Eventf(deployment, v1.EventTypeNormal, "ScalingReplicaSet", "Scaled %s replica set %s to %d", scalingOperation, rs.Name, newScale)
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q}},"spec":{"replicas":%d}}`,
+			deploymentutil.DesiredReplicasAnnotation, rsCopy.Annotations[deploymentutil.DesiredReplicasAnnotation],
+			deploymentutil.MaxReplicasAnnotation, rsCopy.Annotations[deploymentutil.MaxReplicasAnnotation],
+			newScale)
+		_, err = dc.client.AppsV1().ReplicaSets(rsCopy.Namespace).Patch(ctx, rsCopy.Name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err == nil {
+			if dc.rsExpectations != nil {
+				dc.rsExpectations.expect(rsCopy.UID)
+			}
+			rs = rsCopy
+			if sizeNeedsUpdate {
+				scaled = true
+				dc.eventRecorder.Eventf(deployment, v1.EventTypeNormal, "ScalingReplicaSet", "Scaled %s replica set %s to %d", scalingOperation, rs.Name, newScale)
+				dc.auditMutation("ReplicaSet", rsCopy.Namespace, rsCopy.Name, "patch", fmt.Sprintf("replicas %d -> %d", oldScale, newScale), "ScalingReplicaSet")
+			}
 		}
 	}
 	return scaled, rs, err
@@ -435,8 +562,9 @@ func (dc *DeploymentController) scaleReplicaSet(rs *apps.ReplicaSet, newScale in
 // cleanupDeployment is responsible for cleaning up a deployment ie. retains all but the latest N old replica sets
 // where N=d.Spec.RevisionHistoryLimit. Old replica sets are older versions of the podtemplate of a deployment kept
 // around by default 1) for historical reasons and 2) for the ability to rollback a deployment.
-func (dc *DeploymentController) cleanupDeployment(oldRSs []*apps.ReplicaSet, deployment *apps.Deployment) error {
-	if !deploymentutil.HasRevisionHistoryLimit(deployment) {
+func (dc *DeploymentController) cleanupDeployment(ctx context.Context, oldRSs []*apps.ReplicaSet, deployment *apps.Deployment) error {
+	historyTTL, hasHistoryTTL := deploymentutil.GetHistoryTTL(deployment)
+	if !deploymentutil.HasRevisionHistoryLimit(deployment) && !hasHistoryTTL {
 		return nil
 	}
 
@@ -446,44 +574,178 @@ func (dc *DeploymentController) cleanupDeployment(oldRSs []*apps.ReplicaSet, dep
 	}
 	cleanableRSes := controller.FilterReplicaSets(oldRSs, aliveFilter)
 
-	diff := int32(len(cleanableRSes)) - *deployment.Spec.RevisionHistoryLimit
-	if diff <= 0 {
-		return nil
+	sort.Sort(deploymentutil.ReplicaSetsByRevision(cleanableRSes))
+
+	// Within the cleanup grace period after a completed rollout, keep the
+	// immediately-previous revision around so an emergency rollback stays cheap.
+	graceProtected := dc.replicaSetInCleanupGrace(deployment, cleanableRSes)
+	// The most recent revision that completed successfully is never pruned, so rollback
+	// always has a valid target even with a tiny history limit.
+	lastCompleted, hasLastCompleted := deploymentutil.GetLastCompletedRevision(deployment)
+	completedProtected := func(rs *apps.ReplicaSet) bool {
+		if !hasLastCompleted {
+			return false
+		}
+		revision, err := deploymentutil.Revision(rs)
+		return err == nil && revision == lastCompleted
 	}
 
-	sort.Sort(deploymentutil.ReplicaSetsByRevision(cleanableRSes))
-	klog.V(4).Infof("Looking to cleanup old replica sets for deployment %q", deployment.Name)
+	deleted := map[string]bool{}
+	var blockedRSes []string
+	if deploymentutil.HasRevisionHistoryLimit(deployment) {
+		diff := int32(len(cleanableRSes)) - *deployment.Spec.RevisionHistoryLimit
+		klog.V(4).Infof("Looking to cleanup old replica sets for deployment %q", deployment.Name)
 
-	for i := int32(0); i < diff; i++ {
-		rs := cleanableRSes[i]
-		// Avoid delete replica set with non-zero replica counts
-		if rs.Status.Replicas != 0 || *(rs.Spec.Replicas) != 0 || rs.Generation > rs.Status.ObservedGeneration || rs.DeletionTimestamp != nil {
-			continue
+		for i := int32(0); i < diff; i++ {
+			rs := cleanableRSes[i]
+			if rs.Name == graceProtected || completedProtected(rs) {
+				continue
+			}
+			if !cleanableReplicaSet(rs) {
+				// Replica sets slated for cleanup that still run replicas point at a
+				// stuck scale-down; surface them instead of skipping silently.
+				if !deploymentutil.IsReplicaSetRetained(rs) && (rs.Status.Replicas != 0 || *(rs.Spec.Replicas) != 0) {
+					blockedRSes = append(blockedRSes, rs.Name)
+				}
+				continue
+			}
+			klog.V(4).Infof("Trying to cleanup replica set %q for deployment %q", rs.Name, deployment.Name)
+			if err := dc.deleteOldReplicaSet(ctx, rs, deployment); err != nil {
+				// Return error instead of aggregating and continuing DELETEs on the theory
+				// that we may be overloading the api server.
+				return err
+			}
+			deleted[rs.Name] = true
 		}
-		klog.V(4).Infof("Trying to cleanup replica set %q for deployment %q", rs.Name, deployment.Name)
-		if err := dc.client.AppsV1().ReplicaSets(rs.Namespace).Delete(context.TODO(), rs.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
-			// Return error instead of aggregating and continuing DELETEs on the theory
-			// that we may be overloading the api server.
-			return err
+	}
+
+	if err := dc.syncHistoryCleanupBlockedCondition(ctx, deployment, blockedRSes); err != nil {
+		return err
+	}
+
+	// Age out revision history regardless of the count-based limit, if a TTL is declared.
+	if hasHistoryTTL {
+		for _, rs := range cleanableRSes {
+			if deleted[rs.Name] || rs.Name == graceProtected || completedProtected(rs) || !cleanableReplicaSet(rs) {
+				continue
+			}
+			if dc.now().Sub(rs.CreationTimestamp.Time) <= historyTTL {
+				continue
+			}
+			klog.V(4).Infof("Trying to cleanup replica set %q for deployment %q: older than the %v history TTL", rs.Name, deployment.Name, historyTTL)
+			if err := dc.deleteOldReplicaSet(ctx, rs, deployment); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordCompletedRevision remembers the revision that just reached the Complete state in
+// a controller-managed deployment annotation, protecting its replica set from cleanup.
+func (dc *DeploymentController) recordCompletedRevision(ctx context.Context, d *apps.Deployment, newRS *apps.ReplicaSet) {
+	if newRS == nil {
+		return
+	}
+	revision, err := deploymentutil.Revision(newRS)
+	if err != nil || revision == 0 {
+		return
+	}
+	revisionString := strconv.FormatInt(revision, 10)
+	if d.Annotations[deploymentutil.LastCompletedRevisionAnnotation] == revisionString {
+		return
+	}
+	// Stamp the completion time of this rollout together with the completed revision.
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q}}}`,
+		deploymentutil.LastCompletedRevisionAnnotation, revisionString,
+		deploymentutil.RolloutCompletedAtAnnotation, dc.now().UTC().Format(time.RFC3339)))
+	if _, err := dc.client.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.V(2).Infof("Couldn't record completed revision %s for deployment %s/%s: %v", revisionString, d.Namespace, d.Name, err)
+	}
+}
+
+// syncHistoryCleanupBlockedCondition maintains the HistoryCleanupBlocked condition: set
+// while replica sets slated for cleanup still have replicas, removed once cleanup is
+// unblocked. Status is only written when the condition actually changes.
+func (dc *DeploymentController) syncHistoryCleanupBlockedCondition(ctx context.Context, deployment *apps.Deployment, blockedRSes []string) error {
+	current := deploymentutil.GetDeploymentCondition(deployment.Status, deploymentutil.DeploymentHistoryCleanupBlocked)
+	if len(blockedRSes) == 0 {
+		if current == nil {
+			return nil
+		}
+		deploymentutil.RemoveDeploymentCondition(&deployment.Status, deploymentutil.DeploymentHistoryCleanupBlocked)
+	} else {
+		message := fmt.Sprintf("History cleanup is blocked by replica sets that still have replicas: %s", strings.Join(blockedRSes, ", "))
+		if current != nil && current.Message == message {
+			return nil
 		}
+		condition := deploymentutil.NewDeploymentCondition(deploymentutil.DeploymentHistoryCleanupBlocked, v1.ConditionTrue, "OldReplicaSetsNotScaledDown", message)
+		deploymentutil.SetDeploymentCondition(&deployment.Status, *condition)
+	}
+	_, err := dc.client.AppsV1().Deployments(deployment.Namespace).UpdateStatus(ctx, deployment, metav1.UpdateOptions{})
+	return err
+}
+
+// replicaSetInCleanupGrace returns the name of the replica set that is temporarily
+// exempt from cleanup, if any: the highest old revision while the rollout completed less
+// than the declared cleanup grace ago. The replica sets must be sorted by revision.
+func (dc *DeploymentController) replicaSetInCleanupGrace(deployment *apps.Deployment, sortedRSes []*apps.ReplicaSet) string {
+	grace, ok := deploymentutil.GetCleanupGrace(deployment)
+	if !ok || len(sortedRSes) == 0 {
+		return ""
+	}
+	cond := deploymentutil.GetDeploymentCondition(deployment.Status, apps.DeploymentProgressing)
+	if cond == nil || cond.Reason != deploymentutil.NewRSAvailableReason {
+		return ""
 	}
+	if sinceCompletion := dc.now().Sub(cond.LastUpdateTime.Time); sinceCompletion > grace {
+		return ""
+	}
+	return sortedRSes[len(sortedRSes)-1].Name
+}
 
+// cleanableReplicaSet reports whether an old replica set may be deleted by history
+// cleanup: it must be fully scaled down, observed, not already being deleted, and not
+// explicitly retained.
+func cleanableReplicaSet(rs *apps.ReplicaSet) bool {
+	if deploymentutil.IsReplicaSetRetained(rs) {
+		return false
+	}
+	return rs.Status.Replicas == 0 && *(rs.Spec.Replicas) == 0 && rs.Generation <= rs.Status.ObservedGeneration && rs.DeletionTimestamp == nil
+}
+
+// deleteOldReplicaSet deletes a single old replica set as part of history cleanup and
+// records the deletion on the deployment, so disappearing history is explainable from
+// kubectl describe.
+func (dc *DeploymentController) deleteOldReplicaSet(ctx context.Context, rs *apps.ReplicaSet, deployment *apps.Deployment) error {
+	if err := dc.client.AppsV1().ReplicaSets(rs.Namespace).Delete(ctx, rs.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	revision, _ := deploymentutil.Revision(rs)
+	age := dc.now().Sub(rs.CreationTimestamp.Time).Round(time.Second)
+	dc.eventRecorder.Eventf(deployment, v1.EventTypeNormal, "CleanedUpReplicaSet",
+		"Deleted old replica set %q (revision %d, %v old) while cleaning up revision history", rs.Name, revision, age)
+	dc.auditMutation("ReplicaSet", rs.Namespace, rs.Name, "delete", "", "CleanupOldReplicaSets")
 	return nil
 }
 
 // syncDeploymentStatus checks if the status is up-to-date and sync it if necessary
 // 이부분이 etcd에 POD 할당 요청 상태로 업데이트 한다는 부분 아닐까?
-func (dc *DeploymentController) syncDeploymentStatus(allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, d *apps.Deployment) error {
+func (dc *DeploymentController) syncDeploymentStatus(ctx context.Context, allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, d *apps.Deployment) error {
 	newStatus := calculateStatus(allRSs, newRS, d)
 
-	if reflect.DeepEqual(d.Status, newStatus) {
+	// The semantic comparison treats nil and empty condition lists as equal, so
+	// resyncs of steady-state deployments don't generate writes that differ only in
+	// such representation details.
+	if apiequality.Semantic.DeepEqual(d.Status, newStatus) {
 		return nil
 	}
 
-	newDeployment := d
-	newDeployment.Status = newStatus
-	_, err := dc.client.AppsV1().Deployments(newDeployment.Namespace).UpdateStatus(context.TODO(), newDeployment, metav1.UpdateOptions{})
-	return err
+	// Remember Progressing/Available transitions before the old status is overwritten.
+	dc.recordConditionTransitions(ctx, d, &d.Status, &newStatus)
+
+	return dc.writeDeploymentStatus(ctx, d, newStatus)
 }
 
 // calculateStatus calculates the latest status for the provided deployment by looking into the provided replica sets.
@@ -527,10 +789,14 @@ func calculateStatus(allRSs []*apps.ReplicaSet, newRS *apps.ReplicaSet, deployme
 
 // isScalingEvent checks whether the provided deployment has been updated with a scaling event
 // by looking at the desired-replicas annotation in the active replica sets of the deployment.
+// This covers direct edits of .spec.replicas as well as writes through the scale subresource,
+// e.g. by the horizontal pod autoscaler; both only move .spec.replicas away from the value
+// recorded in the replica set annotations. Note that a scaling event may coincide with a
+// template update, in which case the caller applies the scale before starting the rollout.
 //
 // rsList should come from getReplicaSetsForDeployment(d).
-func (dc *DeploymentController) isScalingEvent(d *apps.Deployment, rsList []*apps.ReplicaSet) (bool, error) {
-	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(d, rsList, false)
+func (dc *DeploymentController) isScalingEvent(ctx context.Context, d *apps.Deployment, rsList []*apps.ReplicaSet) (bool, error) {
+	newRS, oldRSs, err := dc.getAllReplicaSetsAndSyncRevision(ctx, d, rsList, false)
 	if err != nil {
 		return false, err
 	}
@@ -17,6 +17,8 @@ limitations under the License.
 package deployment
 
 import (
+	"context"
+	"encoding/json"
 	"math"
 	"testing"
 	"time"
@@ -297,7 +299,7 @@ func TestScale(t *testing.T) {
 				deploymentutil.SetReplicasAnnotations(rs, desiredReplicas, desiredReplicas+deploymentutil.MaxSurge(*test.oldDeployment))
 			}
 
-			if err := dc.scale(test.deployment, test.newRS, test.oldRSs); err != nil {
+			if err := dc.scale(context.TODO(), test.deployment, test.newRS, test.oldRSs); err != nil {
 				t.Errorf("%s: unexpected error: %v", test.name, err)
 				return
 			}
@@ -313,11 +315,23 @@ func TestScale(t *testing.T) {
 				rs := test.oldRSs[i]
 				nameToSize[rs.Name] = *(rs.Spec.Replicas)
 			}
-			// Get all the UPDATE actions and update nameToSize with all the updated sizes.
+			// Get all the scale PATCH actions and update nameToSize with all the
+			// updated sizes.
 			for _, action := range fake.Actions() {
-				rs := action.(testclient.UpdateAction).GetObject().(*apps.ReplicaSet)
-				if !test.wasntUpdated[rs.Name] {
-					nameToSize[rs.Name] = *(rs.Spec.Replicas)
+				patchAction, ok := action.(testclient.PatchAction)
+				if !ok {
+					continue
+				}
+				var patch struct {
+					Spec struct {
+						Replicas *int32 `json:"replicas"`
+					} `json:"spec"`
+				}
+				if err := json.Unmarshal(patchAction.GetPatch(), &patch); err != nil || patch.Spec.Replicas == nil {
+					continue
+				}
+				if !test.wasntUpdated[patchAction.GetName()] {
+					nameToSize[patchAction.GetName()] = *patch.Spec.Replicas
 				}
 			}
 
@@ -413,7 +427,7 @@ func TestDeploymentController_cleanupDeployment(t *testing.T) {
 
 		fake := &fake.Clientset{}
 		informers := informers.NewSharedInformerFactory(fake, controller.NoResyncPeriodFunc())
-		controller, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), fake)
+		controller, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), informers.Policy().V1().PodDisruptionBudgets(), informers.Core().V1().Nodes(), fake)
 		if err != nil {
 			t.Fatalf("error creating Deployment controller: %v", err)
 		}
@@ -433,7 +447,7 @@ func TestDeploymentController_cleanupDeployment(t *testing.T) {
 
 		t.Logf(" &test.revisionHistoryLimit: %d", test.revisionHistoryLimit)
 		d := newDeployment("foo", 1, &test.revisionHistoryLimit, nil, nil, map[string]string{"foo": "bar"})
-		controller.cleanupDeployment(test.oldRSs, d)
+		controller.cleanupDeployment(context.TODO(), test.oldRSs, d)
 
 		gotDeletions := 0
 		for _, action := range fake.Actions() {
@@ -547,7 +561,7 @@ func TestDeploymentController_cleanupDeploymentOrder(t *testing.T) {
 
 		fake := &fake.Clientset{}
 		informers := informers.NewSharedInformerFactory(fake, controller.NoResyncPeriodFunc())
-		controller, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), fake)
+		controller, err := NewDeploymentController(informers.Apps().V1().Deployments(), informers.Apps().V1().ReplicaSets(), informers.Core().V1().Pods(), informers.Policy().V1().PodDisruptionBudgets(), informers.Core().V1().Nodes(), fake)
 		if err != nil {
 			t.Fatalf("error creating Deployment controller: %v", err)
 		}
@@ -565,7 +579,7 @@ func TestDeploymentController_cleanupDeploymentOrder(t *testing.T) {
 		informers.Start(stopCh)
 
 		d := newDeployment("foo", 1, &test.revisionHistoryLimit, nil, nil, map[string]string{"foo": "bar"})
-		controller.cleanupDeployment(test.oldRSs, d)
+		controller.cleanupDeployment(context.TODO(), test.oldRSs, d)
 
 		deletedRSs := sets.String{}
 		for _, action := range fake.Actions() {
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing provides a pre-wired fake deployment controller for downstream
+// projects and e2e authors who want to simulate rollouts without copy-pasting the
+// controller's internal test fixture.
+package testing
+
+import (
+	"context"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/controller/deployment"
+)
+
+// Harness wires a DeploymentController against a fake clientset and hand-populated
+// informer stores. Objects are served from the informer caches, mutations land on the
+// fake client and can be inspected through Actions, and Sync drives one reconciliation
+// at a time, so rollouts can be simulated step by step.
+type Harness struct {
+	// Controller is the controller under test. Hooks such as SetClock or
+	// SetAnalysisProvider may be configured before the first Sync.
+	Controller *deployment.DeploymentController
+	// Client records every API action the controller performs.
+	Client *fake.Clientset
+	// Informers is the factory whose stores back the controller's listers. It is not
+	// started; use the Add helpers to populate the caches deterministically.
+	Informers informers.SharedInformerFactory
+}
+
+// NewHarness returns a harness whose fake client is seeded with the given objects. The
+// informer caches start empty; populate them with the Add helpers.
+func NewHarness(objects ...runtime.Object) (*Harness, error) {
+	client := fake.NewSimpleClientset(objects...)
+	factory := informers.NewSharedInformerFactory(client, controller.NoResyncPeriodFunc())
+	dc, err := deployment.NewDeploymentController(
+		factory.Apps().V1().Deployments(),
+		factory.Apps().V1().ReplicaSets(),
+		factory.Core().V1().Pods(),
+		factory.Policy().V1().PodDisruptionBudgets(),
+		factory.Core().V1().Nodes(),
+		client,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Harness{Controller: dc, Client: client, Informers: factory}, nil
+}
+
+// AddDeployment puts a deployment into the informer cache.
+func (h *Harness) AddDeployment(d *apps.Deployment) error {
+	return h.Informers.Apps().V1().Deployments().Informer().GetIndexer().Add(d)
+}
+
+// AddReplicaSet puts a replica set into the informer cache.
+func (h *Harness) AddReplicaSet(rs *apps.ReplicaSet) error {
+	return h.Informers.Apps().V1().ReplicaSets().Informer().GetIndexer().Add(rs)
+}
+
+// AddPod puts a pod into the informer cache.
+func (h *Harness) AddPod(pod *v1.Pod) error {
+	return h.Informers.Core().V1().Pods().Informer().GetIndexer().Add(pod)
+}
+
+// Sync performs exactly one reconciliation of the named deployment, like one pass of the
+// controller's sync loop, against the current informer contents.
+func (h *Harness) Sync(ctx context.Context, namespace, name string) error {
+	d, err := h.Informers.Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	all, err := h.Informers.Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	rsList := make([]*apps.ReplicaSet, 0, len(all))
+	for _, rs := range all {
+		if metav1.IsControlledBy(rs, d) {
+			rsList = append(rsList, rs)
+		}
+	}
+
+	pods, err := h.Informers.Core().V1().Pods().Lister().Pods(namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	podMap := make(map[types.UID][]*v1.Pod, len(rsList))
+	for _, rs := range rsList {
+		podMap[rs.UID] = []*v1.Pod{}
+	}
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil {
+			continue
+		}
+		if _, ok := podMap[controllerRef.UID]; ok {
+			podMap[controllerRef.UID] = append(podMap[controllerRef.UID], pod)
+		}
+	}
+
+	return h.Controller.ReconcileDeployment(ctx, d.DeepCopy(), rsList, podMap)
+}
+
+// Actions returns the API actions the controller performed so far.
+func (h *Harness) Actions() []core.Action {
+	return h.Client.Actions()
+}
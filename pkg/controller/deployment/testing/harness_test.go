@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+func harnessDeployment(name string, replicas int32) *apps.Deployment {
+	selector := map[string]string{"app": name}
+	maxSurge := intstr.FromInt(1)
+	maxUnavailable := intstr.FromInt(0)
+	return &apps.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			UID:       uuid.NewUUID(),
+			Name:      name,
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Strategy: apps.DeploymentStrategy{
+				Type: apps.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &apps.RollingUpdateDeployment{
+					MaxSurge:       &maxSurge,
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: selector},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "app", Image: "app:v1"}},
+				},
+			},
+		},
+	}
+}
+
+func TestHarnessDrivesOneSync(t *testing.T) {
+	d := harnessDeployment("app", 3)
+	h, err := NewHarness(d)
+	if err != nil {
+		t.Fatalf("error building harness: %v", err)
+	}
+	if err := h.AddDeployment(d); err != nil {
+		t.Fatalf("error adding deployment: %v", err)
+	}
+
+	if err := h.Sync(context.TODO(), d.Namespace, d.Name); err != nil {
+		t.Fatalf("unexpected sync error: %v", err)
+	}
+
+	created := false
+	for _, action := range h.Actions() {
+		if action.GetVerb() == "create" && action.GetResource().Resource == "replicasets" {
+			created = true
+		}
+	}
+	if !created {
+		t.Errorf("expected the first sync to create a replica set, got actions: %v", h.Actions())
+	}
+}
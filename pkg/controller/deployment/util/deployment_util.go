@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -38,6 +39,7 @@ import (
 	appsclient "k8s.io/client-go/kubernetes/typed/apps/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	"k8s.io/klog/v2"
+	corev1 "k8s.io/kubernetes/pkg/apis/core/v1"
 	"k8s.io/kubernetes/pkg/controller"
 	labelsutil "k8s.io/kubernetes/pkg/util/labels"
 	"k8s.io/utils/integer"
@@ -56,6 +58,206 @@ const (
 	// is deployment.spec.replicas + maxSurge. Used by the underlying replica sets to estimate their
 	// proportions in case the deployment has surge replicas.
 	MaxReplicasAnnotation = "deployment.kubernetes.io/max-replicas"
+	// ScaleDownOrderAnnotation controls the order in which a rolling update scales down
+	// multiple old replica sets. Valid values are OldestFirst (the default), NewestFirst
+	// and LeastReadyFirst; unknown values fall back to OldestFirst.
+	ScaleDownOrderAnnotation = "deployment.kubernetes.io/scale-down-order"
+	// RollingBatchSizeAnnotation makes a rolling update progress in fixed batches: the
+	// controller replaces at most this many pods per iteration and waits for the whole
+	// batch to become available before starting the next one. Values that are not positive
+	// integers are ignored.
+	RollingBatchSizeAnnotation = "deployment.kubernetes.io/rolling-batch-size"
+	// SurgePodTTLAnnotation is the maximum time a new-revision pod may stay unready before
+	// the controller deletes it so it gets recreated, working around node-local flakes.
+	// The value is a Go duration, e.g. "10m". Invalid values are ignored.
+	SurgePodTTLAnnotation = "deployment.kubernetes.io/surge-pod-ttl"
+	// SurgePodFailureActionAnnotation is the action taken when recreating unready surge
+	// pods has been retried too often. Valid values are Ignore (the default) and Pause.
+	SurgePodFailureActionAnnotation = "deployment.kubernetes.io/surge-pod-failure-action"
+	// SurgePodRestartsAnnotation records on a replica set how many of its unready pods the
+	// controller has already deleted because they exceeded the surge pod TTL.
+	SurgePodRestartsAnnotation = "deployment.kubernetes.io/surge-pod-restarts"
+	// RollbackHistoryAnnotation is a controller-managed, bounded audit trail of the
+	// rollbacks performed for this deployment. Each entry records the source and target
+	// revision, the trigger and a timestamp.
+	RollbackHistoryAnnotation = "deployment.kubernetes.io/rollback-history"
+	// RollbackTriggerAnnotation names what initiated a pending rollback request (e.g.
+	// CrashLoop). It is recorded in the rollback history and cleared with the request;
+	// absent values are recorded as "user".
+	RollbackTriggerAnnotation = "deployment.kubernetes.io/rollback-trigger"
+	// CleanupGraceAnnotation delays pruning of the immediately-previous revision: after a
+	// rollout completes, the replica set with the highest old revision is exempt from
+	// history cleanup for this Go duration, keeping emergency rollbacks cheap. Invalid
+	// values are ignored.
+	CleanupGraceAnnotation = "deployment.kubernetes.io/cleanup-grace"
+	// HistoryTTLAnnotation ages out revision history: zero-replica old replica sets whose
+	// creation is older than this Go duration are deleted regardless of
+	// revisionHistoryLimit, so rarely-updated deployments don't hoard ancient revisions.
+	HistoryTTLAnnotation = "deployment.kubernetes.io/history-ttl"
+	// RollbackBakeAnnotation turns rollbacks into a two-phase operation: after the
+	// template is restored the deployment is paused for the given duration, and the
+	// rollback is only reported complete once the restored revision stayed available for
+	// the whole bake. Invalid values are ignored.
+	RollbackBakeAnnotation = "deployment.kubernetes.io/rollback-bake"
+	// RollbackVerifyUntilAnnotation is controller-managed and records when the
+	// verification bake of the last rollback ends.
+	RollbackVerifyUntilAnnotation = "deployment.kubernetes.io/rollback-verify-until"
+	// RollbackVerifyAvailableAnnotation is controller-managed and marks that the
+	// restored revision became available at least once during the verification bake;
+	// only from then on does a loss of availability fail the verification.
+	RollbackVerifyAvailableAnnotation = "deployment.kubernetes.io/rollback-verify-available"
+	// RevisionCompanionKeysAnnotation lists deployment annotation keys (comma separated)
+	// that are snapshotted onto each revision's replica set and restored on rollback, so
+	// companion metadata such as HPA hints travels with the revision even for keys the
+	// regular annotation copy skips.
+	RevisionCompanionKeysAnnotation = "deployment.kubernetes.io/revision-companion-keys"
+	// PinnedRevisionAnnotation freezes a deployment on a specific revision: whenever the
+	// live pod template drifts from that revision's template, the controller reverts it
+	// through the rollback path and records the reversion. Useful for locking workloads
+	// down during incidents.
+	PinnedRevisionAnnotation = "deployment.kubernetes.io/pinned-revision"
+	// RollbackRequestAnnotation carries a structured rollback request handled by the
+	// controller, replacing the deprecated rollbackTo round-trip annotation. The value is
+	// JSON, e.g. {"revision": 3}; revision 0 means the previous revision. The controller
+	// clears the request once it is handled and reports the outcome in a RollbackComplete
+	// condition.
+	RollbackRequestAnnotation = "deployment.kubernetes.io/rollback-request"
+	// RollbackMaxAgeAnnotation limits rollbacks to target revisions whose replica set is
+	// younger than the given Go duration, guarding against reviving templates whose
+	// dependencies no longer exist. Invalid values are ignored.
+	RollbackMaxAgeAnnotation = "deployment.kubernetes.io/rollback-max-age"
+	// RollbackMaxRevisionsAnnotation limits rollbacks to the last N revisions before the
+	// current one. Values that are not positive integers are ignored.
+	RollbackMaxRevisionsAnnotation = "deployment.kubernetes.io/rollback-max-revisions"
+	// RSNamingSchemeAnnotation selects how new replica sets are named. The default scheme
+	// suffixes the deployment name with the pod-template-hash; RevisionHash additionally
+	// embeds the revision number so names are human-interpretable in dashboards and
+	// logs. Unknown values fall back to the default.
+	RSNamingSchemeAnnotation = "deployment.kubernetes.io/rs-naming-scheme"
+	// NoOrphanAdoptionAnnotation disables automatic adoption of orphaned replica sets for
+	// this deployment: only replica sets that already have a controller reference are
+	// claimed, protecting hand-made replica sets with overlapping selectors.
+	NoOrphanAdoptionAnnotation = "deployment.kubernetes.io/no-orphan-adoption"
+	// ControllerRevisionsAnnotation opts a deployment into recording every pod template
+	// revision as a ControllerRevision object in addition to the retained replica sets,
+	// allowing rollback targets to survive aggressive replica set cleanup.
+	ControllerRevisionsAnnotation = "deployment.kubernetes.io/controller-revisions"
+	// ApprovalRequiredAnnotation gates promotion on an explicit sign-off: while set to
+	// "true", the controller does not scale down old replica sets until the current new
+	// revision is named in ApprovedRevisionAnnotation, surfacing an AwaitingApproval
+	// condition in the meantime. Unlike spec.paused this keeps the step context.
+	ApprovalRequiredAnnotation = "deployment.kubernetes.io/approval-required"
+	// ApprovedRevisionAnnotation carries the revision number a human or automated
+	// approver signed off on.
+	ApprovedRevisionAnnotation = "deployment.kubernetes.io/approved-revision"
+	// PauseCauseAnnotation is controller-managed and names why the controller paused the
+	// deployment (e.g. CrashLoop, SurgePodRetriesExhausted). It becomes the reason of
+	// the RolloutPaused condition, distinguishing controller-initiated pauses from user
+	// ones, and is cleared when the deployment resumes.
+	PauseCauseAnnotation = "deployment.kubernetes.io/pause-cause"
+	// RolloutStartedAtAnnotation is controller-managed and records when the rollout of
+	// the current revision started (RFC3339), i.e. when its replica set was created.
+	RolloutStartedAtAnnotation = "deployment.kubernetes.io/rollout-started-at"
+	// RolloutCompletedAtAnnotation is controller-managed and records when the rollout of
+	// the current revision completed (RFC3339), enabling deploy-duration SLO measurement
+	// directly from the API object.
+	RolloutCompletedAtAnnotation = "deployment.kubernetes.io/rollout-completed-at"
+	// StrategyStatusAnnotation is controller-managed JSON describing the state of the
+	// richer annotation-driven strategies (batched rollouts, dual-revision splits),
+	// including the active holds, since observedGeneration alone can't express
+	// multi-phase progress.
+	StrategyStatusAnnotation = "deployment.kubernetes.io/strategy-status"
+	// CurrentRevisionHashAnnotation is controller-managed and names the pod-template-hash
+	// of the current (new) revision, like StatefulSet's updateRevision, so clients don't
+	// have to parse replica set annotations to identify what's live.
+	CurrentRevisionHashAnnotation = "deployment.kubernetes.io/current-revision-hash"
+	// PreviousRevisionHashAnnotation is controller-managed and names the
+	// pod-template-hash of the latest old revision.
+	PreviousRevisionHashAnnotation = "deployment.kubernetes.io/previous-revision-hash"
+	// RevisionBreakdownAnnotation is controller-managed and lists the current replica
+	// split per revision as "<hash>=<replicas>/<ready>/<available>" entries, so traffic
+	// shifting and canary tooling doesn't have to join replica set objects manually.
+	RevisionBreakdownAnnotation = "deployment.kubernetes.io/revision-breakdown"
+	// ConditionHistoryAnnotation is controller-managed and keeps a bounded history of the
+	// last Progressing/Available condition transitions (status, reason, timestamp),
+	// making flapping rollouts diagnosable without an external event pipeline.
+	ConditionHistoryAnnotation = "deployment.kubernetes.io/condition-history"
+	// TerminatingReplicasAnnotation is controller-managed and counts the deployment's
+	// pods that are still terminating, distinguishing "pods gone" from "pods on the way
+	// out" during Recreate rollouts and scale-downs.
+	TerminatingReplicasAnnotation = "deployment.kubernetes.io/terminating-replicas"
+	// ProgressPercentAnnotation is controller-managed and carries the percentage of the
+	// desired replicas that are updated and available, maintained during rollouts so UIs
+	// don't have to reimplement the availability math.
+	ProgressPercentAnnotation = "deployment.kubernetes.io/progress-percent"
+	// EstimatedCompletionAnnotation is controller-managed and carries the estimated
+	// completion time (RFC3339) of the in-flight rollout, derived from the observed
+	// pod-availability rate. It is removed when the rollout completes.
+	EstimatedCompletionAnnotation = "deployment.kubernetes.io/estimated-completion"
+	// LastCompletedRevisionAnnotation is controller-managed and records the most recent
+	// revision that reached the Complete state. The replica set carrying it is never
+	// pruned by history cleanup, so rollback always has a valid target.
+	LastCompletedRevisionAnnotation = "deployment.kubernetes.io/last-completed-revision"
+	// RetainReplicaSetAnnotation protects an old replica set from history cleanup even
+	// when it exceeds revisionHistoryLimit or the history TTL, letting operators keep a
+	// known-good golden revision indefinitely.
+	RetainReplicaSetAnnotation = "deployment.kubernetes.io/retain"
+	// RevisionBlockedAnnotation marks a replica set's revision as a known-bad rollback
+	// target. The controller refuses to roll back to blocked revisions and, for automatic
+	// rollbacks, falls through to the next eligible revision instead.
+	RevisionBlockedAnnotation = "deployment.kubernetes.io/revision-blocked"
+	// RollbackDryRunAnnotation makes the controller evaluate a rollback request without
+	// mutating the deployment spec: the target revision is located, the resulting
+	// template change is reported in an event, and the request is cleared.
+	RollbackDryRunAnnotation = "deployment.kubernetes.io/rollback-dry-run"
+	// CrashLoopThresholdAnnotation enables automatic rollback (or pause) of a rollout:
+	// when at least this many new-revision pods are in CrashLoopBackOff within the
+	// crash-loop window, the controller reacts instead of waiting for the progress
+	// deadline. Values that are not positive integers are ignored.
+	CrashLoopThresholdAnnotation = "deployment.kubernetes.io/crash-loop-threshold"
+	// CrashLoopWindowAnnotation bounds how long after the new replica set's creation the
+	// crash-loop threshold is checked. The value is a Go duration and defaults to 10m.
+	CrashLoopWindowAnnotation = "deployment.kubernetes.io/crash-loop-window"
+	// CrashLoopActionAnnotation selects the reaction to the crash-loop threshold. Valid
+	// values are Rollback (the default) and Pause.
+	CrashLoopActionAnnotation = "deployment.kubernetes.io/crash-loop-action"
+	// InPlaceMetadataKeysAnnotation lists template label and annotation keys (comma
+	// separated) whose changes do not warrant a new revision. Edits restricted to these
+	// keys are applied in place to the existing new replica set instead of triggering a
+	// rollout, avoiding pod churn for ownership or observability metadata.
+	InPlaceMetadataKeysAnnotation = "deployment.kubernetes.io/in-place-metadata-keys"
+	// AutoPromoteAfterAnnotation resumes a paused deployment automatically once it has
+	// stayed paused and healthy for the given bake duration, e.g. "30m". Pauses that the
+	// controller has not yet acknowledged with a paused Progressing condition, and
+	// deployments without minimum availability, are not promoted. Invalid values are
+	// ignored.
+	AutoPromoteAfterAnnotation = "deployment.kubernetes.io/auto-promote-after"
+	// DualRevisionRatioAnnotation keeps the deployment at a steady split between the new
+	// revision and the latest old revision instead of collapsing onto the new one. The
+	// value is "<new>:<old>", e.g. "90:10". Scaling events keep the split via proportional
+	// scaling. Invalid values are ignored. Deployments using a ratio should disable the
+	// progress deadline, as they intentionally never complete.
+	DualRevisionRatioAnnotation = "deployment.kubernetes.io/dual-revision-ratio"
+	// StatusRefreshAnnotation is a nonce CLI tooling can set to request that the
+	// controller recomputes and persists fresh status for the deployment. The controller
+	// echoes the nonce into StatusRefreshedAnnotation once the requested sync completed,
+	// bounding status staleness for callers that poll it.
+	StatusRefreshAnnotation = "deployment.kubernetes.io/status-refresh"
+	// StatusRefreshedAnnotation is the controller's echo of StatusRefreshAnnotation.
+	StatusRefreshedAnnotation = "deployment.kubernetes.io/status-refreshed"
+	// RevisionSpreadAnnotation opts a deployment into revision spreading: pods of a new
+	// replica set get a preferred anti-affinity against the pod-template-hash labels of
+	// the old revisions, so surge pods prefer nodes that don't run the old revision.
+	RevisionSpreadAnnotation = "deployment.kubernetes.io/spread-revisions"
+	// QuotaPreflightAnnotation enables a preflight check of the namespace's ResourceQuota
+	// headroom before the controller creates surge pods. When the surge does not fit the
+	// rollout falls back to zero surge instead of burning retries on failing pod creates.
+	QuotaPreflightAnnotation = "deployment.kubernetes.io/quota-preflight"
+	// FreezeWindowsAnnotation declares time windows during which the controller does not
+	// advance rollouts of the deployment. The value is a comma-separated list of daily
+	// HH:MM-HH:MM ranges in UTC; ranges may wrap around midnight. Invalid entries are
+	// ignored.
+	FreezeWindowsAnnotation = "deployment.kubernetes.io/freeze-windows"
 
 	// RollbackRevisionNotFound is not found rollback event reason
 	RollbackRevisionNotFound = "DeploymentRollbackRevisionNotFound"
@@ -63,6 +265,9 @@ const (
 	RollbackTemplateUnchanged = "DeploymentRollbackTemplateUnchanged"
 	// RollbackDone is the done rollback event reason
 	RollbackDone = "DeploymentRollback"
+	// RollbackConsolidated is the event reason used when the capacity left on stale replica
+	// sets by an aborted rollout is consolidated back onto the rollback target.
+	RollbackConsolidated = "DeploymentRollbackConsolidated"
 
 	// Reasons for deployment conditions
 	//
@@ -98,8 +303,70 @@ const (
 	// MinimumReplicasUnavailable is added in a deployment when it doesn't have the minimum required replicas
 	// available.
 	MinimumReplicasUnavailable = "MinimumReplicasUnavailable"
+
+	// SurgeBlockedByQuotaReason is the event reason used when the quota preflight finds no
+	// ResourceQuota headroom for surge pods and the rollout falls back to zero surge.
+	SurgeBlockedByQuotaReason = "SurgeBlockedByQuota"
+
+	// RollbackBlockedReason is used when a rollback request targets a revision that is
+	// marked bad.
+	RollbackBlockedReason = "RollbackBlocked"
+	// RollbackOutOfWindowReason is used when a rollback request targets a revision
+	// outside the deployment's rollback eligibility window.
+	RollbackOutOfWindowReason = "RollbackOutOfWindow"
+
+	// TooManyNodesUnavailableReason is set on the RolloutHeld condition while rollout
+	// progression is held because too many nodes are unschedulable or not ready.
+	TooManyNodesUnavailableReason = "TooManyNodesUnavailable"
+	// HeldByFreezeWindowReason is set on the RolloutHeld condition while rollout
+	// progression is held by a declared freeze window.
+	HeldByFreezeWindowReason = "FreezeWindow"
+	// HeldByAnalysisReason is set on the RolloutHeld condition while promotion waits for
+	// an inconclusive analysis run.
+	HeldByAnalysisReason = "AwaitingAnalysis"
+	// UserPausedReason is set on the RolloutPaused condition when spec.paused was set by
+	// a user; controller-initiated pauses carry the cause recorded in
+	// PauseCauseAnnotation instead.
+	UserPausedReason = "UserPaused"
 )
 
+// DeploymentRolloutHeld is a condition added to a deployment while the controller holds
+// rollout progression for cluster-level reasons, e.g. widespread node unavailability.
+const DeploymentRolloutHeld apps.DeploymentConditionType = "RolloutHeld"
+
+// DeploymentRolloutPaused is a condition present exactly while a deployment is paused.
+// Unlike the Unknown Progressing condition it is dedicated, so tooling can reliably
+// detect paused deployments and, via lastTransitionTime, how long they have been paused.
+const DeploymentRolloutPaused apps.DeploymentConditionType = "RolloutPaused"
+
+// DeploymentRollbackBlocked is a condition added to a deployment when a rollback request
+// had to be rejected because its target revision is marked bad.
+const DeploymentRollbackBlocked apps.DeploymentConditionType = "RollbackBlocked"
+
+// DeploymentRollbackComplete is a condition reporting the outcome of the most recent
+// rollback request handled by the controller.
+const DeploymentRollbackComplete apps.DeploymentConditionType = "RollbackComplete"
+
+// DeploymentHistoryCleanupBlocked is a condition added while revision history cleanup
+// cannot prune replica sets because they still have replicas.
+const DeploymentHistoryCleanupBlocked apps.DeploymentConditionType = "HistoryCleanupBlocked"
+
+// DeploymentReplicaSetCreateBlocked is a condition added while a replica set of the
+// deployment cannot create pods because of ResourceQuota or LimitRange restrictions.
+const DeploymentReplicaSetCreateBlocked apps.DeploymentConditionType = "ReplicaSetCreateBlocked"
+
+// DeploymentImagePullBlocked is a condition added while new-revision pods cannot pull
+// their images, naming the failing image reference.
+const DeploymentImagePullBlocked apps.DeploymentConditionType = "ImagePullBlocked"
+
+// DeploymentAwaitingApproval is a condition present while promotion of the new revision
+// waits for an external approval signal.
+const DeploymentAwaitingApproval apps.DeploymentConditionType = "AwaitingApproval"
+
+// DeploymentRolloutDeadlocked is a condition added when the declared rollout budgets
+// (maxSurge and maxUnavailable) both resolve to zero for the current replica count.
+const DeploymentRolloutDeadlocked apps.DeploymentConditionType = "RolloutDeadlocked"
+
 // NewDeploymentCondition creates a new deployment condition.
 func NewDeploymentCondition(condType apps.DeploymentConditionType, status v1.ConditionStatus, reason, message string) *apps.DeploymentCondition {
 	return &apps.DeploymentCondition{
@@ -232,6 +499,10 @@ func Revision(obj runtime.Object) (int64, error) {
 func SetNewReplicaSetAnnotations(deployment *apps.Deployment, newRS *apps.ReplicaSet, newRevision string, exists bool, revHistoryLimitInChars int) bool {
 	// First, copy deployment's annotations (except for apply and revision annotations)
 	annotationChanged := copyDeploymentAnnotationsToReplicaSet(deployment, newRS)
+	// Snapshot companion annotation values so they can be restored on rollback.
+	if SnapshotCompanionAnnotations(deployment, newRS) {
+		annotationChanged = true
+	}
 	// Then, update replica set's revision annotation
 	if newRS.Annotations == nil {
 		newRS.Annotations = make(map[string]string)
@@ -292,19 +563,133 @@ func SetNewReplicaSetAnnotations(deployment *apps.Deployment, newRS *apps.Replic
 }
 
 var annotationsToSkip = map[string]bool{
-	v1.LastAppliedConfigAnnotation: true,
-	RevisionAnnotation:             true,
-	RevisionHistoryAnnotation:      true,
-	DesiredReplicasAnnotation:      true,
-	MaxReplicasAnnotation:          true,
-	apps.DeprecatedRollbackTo:      true,
+	v1.LastAppliedConfigAnnotation:  true,
+	RevisionAnnotation:              true,
+	RevisionHistoryAnnotation:       true,
+	DesiredReplicasAnnotation:       true,
+	MaxReplicasAnnotation:           true,
+	apps.DeprecatedRollbackTo:       true,
+	RollbackHistoryAnnotation:       true,
+	RollbackTriggerAnnotation:       true,
+	RollbackVerifyUntilAnnotation:     true,
+	RollbackVerifyAvailableAnnotation: true,
+	LastCompletedRevisionAnnotation: true,
+	EstimatedCompletionAnnotation:   true,
+	ProgressPercentAnnotation:       true,
+	TerminatingReplicasAnnotation:   true,
+	ConditionHistoryAnnotation:      true,
+	RevisionBreakdownAnnotation:     true,
+	StrategyStatusAnnotation:        true,
+	CurrentRevisionHashAnnotation:   true,
+	PreviousRevisionHashAnnotation:  true,
+	RolloutStartedAtAnnotation:      true,
+	RolloutCompletedAtAnnotation:    true,
+	PauseCauseAnnotation:            true,
+}
+
+// companionSnapshotPrefix namespaces the per-revision snapshots of companion annotation
+// values kept on replica sets.
+const companionSnapshotPrefix = "snapshot.deployment.kubernetes.io/"
+
+// annotationPropagation holds the controller-wide include/exclude glob patterns applied
+// when copying deployment annotations to replica sets. Empty means everything that is
+// not skipped propagates, which matches the historical behavior.
+var annotationPropagation struct {
+	include []string
+	exclude []string
+}
+
+// SetAnnotationPropagationPolicy configures which deployment annotations propagate to
+// replica sets. Patterns use path.Match syntax; exclusions win over inclusions, and an
+// empty include list means everything propagates. Intended to be called once at
+// controller startup.
+func SetAnnotationPropagationPolicy(include, exclude []string) {
+	annotationPropagation.include = include
+	annotationPropagation.exclude = exclude
+}
+
+// annotationPropagationAllowed applies the configured include/exclude globs to an
+// annotation key.
+func annotationPropagationAllowed(key string) bool {
+	for _, pattern := range annotationPropagation.exclude {
+		if ok, _ := path.Match(pattern, key); ok {
+			return false
+		}
+	}
+	if len(annotationPropagation.include) == 0 {
+		return true
+	}
+	for _, pattern := range annotationPropagation.include {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // skipCopyAnnotation returns true if we should skip copying the annotation with the given annotation key
 // TODO: How to decide which annotations should / should not be copied?
 //       See https://github.com/kubernetes/kubernetes/pull/20035#issuecomment-179558615
 func skipCopyAnnotation(key string) bool {
-	return annotationsToSkip[key]
+	return annotationsToSkip[key] || strings.HasPrefix(key, companionSnapshotPrefix)
+}
+
+// getCompanionKeys returns the deployment annotation keys snapshotted per revision.
+func getCompanionKeys(deployment *apps.Deployment) []string {
+	annotationValue, ok := deployment.Annotations[RevisionCompanionKeysAnnotation]
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, key := range strings.Split(annotationValue, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SnapshotCompanionAnnotations copies the values of the deployment's companion keys into
+// prefixed snapshot annotations on the replica set, returning true when the replica set
+// changed. Keys absent on the deployment have their snapshot removed.
+func SnapshotCompanionAnnotations(deployment *apps.Deployment, rs *apps.ReplicaSet) bool {
+	changed := false
+	for _, key := range getCompanionKeys(deployment) {
+		snapshotKey := companionSnapshotPrefix + key
+		value, ok := deployment.Annotations[key]
+		if !ok {
+			if _, has := rs.Annotations[snapshotKey]; has {
+				delete(rs.Annotations, snapshotKey)
+				changed = true
+			}
+			continue
+		}
+		if rs.Annotations[snapshotKey] != value {
+			if rs.Annotations == nil {
+				rs.Annotations = make(map[string]string)
+			}
+			rs.Annotations[snapshotKey] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// RestoreCompanionAnnotations restores the deployment's companion keys from the replica
+// set's snapshot annotations during a rollback. Keys without a snapshot are removed from
+// the deployment.
+func RestoreCompanionAnnotations(deployment *apps.Deployment, rs *apps.ReplicaSet) {
+	for _, key := range getCompanionKeys(deployment) {
+		value, ok := rs.Annotations[companionSnapshotPrefix+key]
+		if !ok {
+			delete(deployment.Annotations, key)
+			continue
+		}
+		if deployment.Annotations == nil {
+			deployment.Annotations = make(map[string]string)
+		}
+		deployment.Annotations[key] = value
+	}
 }
 
 // copyDeploymentAnnotationsToReplicaSet copies deployment's annotations to replica set's annotations,
@@ -319,7 +704,7 @@ func copyDeploymentAnnotationsToReplicaSet(deployment *apps.Deployment, rs *apps
 		// newRS revision is updated automatically in getNewReplicaSet, and the deployment's revision number is then updated
 		// by copying its newRS revision number. We should not copy deployment's revision to its newRS, since the update of
 		// deployment revision number may fail (revision becomes stale) and the revision number in newRS is more reliable.
-		if _, exist := rs.Annotations[k]; skipCopyAnnotation(k) || (exist && rs.Annotations[k] == v) {
+		if _, exist := rs.Annotations[k]; skipCopyAnnotation(k) || !annotationPropagationAllowed(k) || (exist && rs.Annotations[k] == v) {
 			continue
 		}
 		rs.Annotations[k] = v
@@ -627,25 +1012,377 @@ func ListPods(deployment *apps.Deployment, rsList []*apps.ReplicaSet, getPodList
 	return owned, nil
 }
 
+// GetCleanupGrace returns how long after rollout completion the immediately-previous
+// revision stays exempt from history cleanup, as requested by the deployment's
+// annotations.
+func GetCleanupGrace(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, CleanupGraceAnnotation)
+}
+
+// GetHistoryTTL returns the age at which zero-replica old replica sets are cleaned up
+// regardless of the revision history limit, as requested by the deployment's annotations.
+func GetHistoryTTL(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, HistoryTTLAnnotation)
+}
+
+// GetRollbackBake returns the verification bake duration for rollbacks, as requested by
+// the deployment's annotations.
+func GetRollbackBake(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, RollbackBakeAnnotation)
+}
+
+// GetRollbackMaxAge returns the maximum age a rollback target revision may have, as
+// requested by the deployment's annotations.
+func GetRollbackMaxAge(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, RollbackMaxAgeAnnotation)
+}
+
+// GetRollbackMaxRevisions returns how many revisions back a rollback may reach, as
+// requested by the deployment's annotations.
+func GetRollbackMaxRevisions(deployment *apps.Deployment) (int64, bool) {
+	annotationValue, ok := deployment.Annotations[RollbackMaxRevisionsAnnotation]
+	if !ok {
+		return 0, false
+	}
+	maxRevisions, err := strconv.ParseInt(annotationValue, 10, 64)
+	if err != nil || maxRevisions <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a revision count", annotationValue, RollbackMaxRevisionsAnnotation, deployment.Name)
+		return 0, false
+	}
+	return maxRevisions, true
+}
+
+// RSNamingSchemeRevisionHash names new replica sets <deployment>-<revision>-<hash>.
+const RSNamingSchemeRevisionHash = "RevisionHash"
+
+// NewRSName returns the name for a new replica set of the deployment, according to the
+// deployment's naming scheme annotation. All schemes are deterministic in the revision
+// and pod-template-hash to keep replica set creation idempotent.
+func NewRSName(deployment *apps.Deployment, revision, podTemplateSpecHash string) string {
+	if deployment.Annotations[RSNamingSchemeAnnotation] == RSNamingSchemeRevisionHash {
+		return deployment.Name + "-" + revision + "-" + podTemplateSpecHash
+	}
+	return deployment.Name + "-" + podTemplateSpecHash
+}
+
+// GetPinnedRevision returns the revision the deployment is pinned to, if any.
+func GetPinnedRevision(deployment *apps.Deployment) (int64, bool) {
+	annotationValue, ok := deployment.Annotations[PinnedRevisionAnnotation]
+	if !ok {
+		return 0, false
+	}
+	revision, err := strconv.ParseInt(annotationValue, 10, 64)
+	if err != nil || revision <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a revision", annotationValue, PinnedRevisionAnnotation, deployment.Name)
+		return 0, false
+	}
+	return revision, true
+}
+
+// GetLastCompletedRevision returns the most recent revision of the deployment that
+// reached the Complete state, if the controller has recorded one.
+func GetLastCompletedRevision(deployment *apps.Deployment) (int64, bool) {
+	annotationValue, ok := deployment.Annotations[LastCompletedRevisionAnnotation]
+	if !ok {
+		return 0, false
+	}
+	revision, err := strconv.ParseInt(annotationValue, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return revision, true
+}
+
+// IsReplicaSetRetained reports whether the replica set is protected from history cleanup.
+func IsReplicaSetRetained(rs *apps.ReplicaSet) bool {
+	return rs.Annotations[RetainReplicaSetAnnotation] == "true"
+}
+
+// IsRevisionBlocked reports whether the replica set's revision is marked as a forbidden
+// rollback target.
+func IsRevisionBlocked(rs *apps.ReplicaSet) bool {
+	return rs.Annotations[RevisionBlockedAnnotation] == "true"
+}
+
+// FindRollbackTarget returns the replica set carrying the requested revision. When that
+// revision is blocked and fallback is allowed, the newest older unblocked revision is
+// returned instead; wasBlocked reports whether the requested revision was blocked. A nil
+// replica set means no eligible target exists.
+func FindRollbackTarget(allRSs []*apps.ReplicaSet, revision int64, fallback bool) (*apps.ReplicaSet, bool) {
+	var exact, fallbackRS *apps.ReplicaSet
+	fallbackRevision := int64(0)
+	for _, rs := range allRSs {
+		if rs == nil {
+			continue
+		}
+		v, err := Revision(rs)
+		if err != nil {
+			klog.V(4).Infof("Unable to extract revision from replica set %q: %v", rs.Name, err)
+			continue
+		}
+		switch {
+		case v == revision:
+			exact = rs
+		case v < revision && v > fallbackRevision && !IsRevisionBlocked(rs):
+			fallbackRS = rs
+			fallbackRevision = v
+		}
+	}
+	if exact == nil {
+		return nil, false
+	}
+	if !IsRevisionBlocked(exact) {
+		return exact, false
+	}
+	if fallback {
+		return fallbackRS, true
+	}
+	return nil, true
+}
+
+// maxRollbackHistoryEntries bounds the rollback history annotation.
+const maxRollbackHistoryEntries = 10
+
+// RecordRollback appends an entry to the deployment's rollback history annotation,
+// dropping the oldest entries beyond the bound. Entries have the form
+// "<from>-><to>:<trigger>:<RFC3339 timestamp>".
+func RecordRollback(deployment *apps.Deployment, fromRevision, toRevision int64, trigger string, now time.Time) {
+	if trigger == "" {
+		trigger = "user"
+	}
+	entry := fmt.Sprintf("%d->%d:%s:%s", fromRevision, toRevision, trigger, now.UTC().Format(time.RFC3339))
+	var history []string
+	if existing := deployment.Annotations[RollbackHistoryAnnotation]; existing != "" {
+		history = strings.Split(existing, ",")
+	}
+	history = append(history, entry)
+	if len(history) > maxRollbackHistoryEntries {
+		history = history[len(history)-maxRollbackHistoryEntries:]
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	deployment.Annotations[RollbackHistoryAnnotation] = strings.Join(history, ",")
+}
+
+// TemplateDiffSummary returns a short human-readable summary of what would change when
+// moving from the current pod template to the target one, suitable for events. Image
+// changes are named explicitly; any remaining differences are summarized generically.
+func TemplateDiffSummary(current, target *v1.PodTemplateSpec) string {
+	currentImages := make(map[string]string, len(current.Spec.Containers))
+	for _, container := range current.Spec.Containers {
+		currentImages[container.Name] = container.Image
+	}
+	var changes []string
+	for _, container := range target.Spec.Containers {
+		currentImage, ok := currentImages[container.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("container %q added", container.Name))
+			continue
+		}
+		if currentImage != container.Image {
+			changes = append(changes, fmt.Sprintf("container %q image %s -> %s", container.Name, currentImage, container.Image))
+		}
+		delete(currentImages, container.Name)
+	}
+	for name := range currentImages {
+		changes = append(changes, fmt.Sprintf("container %q removed", name))
+	}
+	sort.Strings(changes)
+	if len(changes) == 0 && !EqualIgnoreHash(current, target) {
+		changes = append(changes, "other pod template fields differ")
+	}
+	return strings.Join(changes, ", ")
+}
+
+// RetainedRevisions returns the sorted revision numbers of the given replica sets, for
+// messages that explain which rollback targets exist.
+func RetainedRevisions(allRSs []*apps.ReplicaSet) []int64 {
+	var revisions []int64
+	for _, rs := range allRSs {
+		if rs == nil {
+			continue
+		}
+		if v, err := Revision(rs); err == nil && v > 0 {
+			revisions = append(revisions, v)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+	return revisions
+}
+
+// InjectRevisionSpreadAffinity adds a preferred pod anti-affinity against the given old
+// revision hashes to the template, so pods created from it prefer nodes that don't run
+// pods of the old revisions. Terms injected here are controller-owned and are ignored by
+// EqualIgnoreHash when templates are compared.
+func InjectRevisionSpreadAffinity(template *v1.PodTemplateSpec, oldHashes []string) {
+	if len(oldHashes) == 0 {
+		return
+	}
+	sort.Strings(oldHashes)
+	term := v1.WeightedPodAffinityTerm{
+		Weight: 100,
+		PodAffinityTerm: v1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      apps.DefaultDeploymentUniqueLabelKey,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   oldHashes,
+				}},
+			},
+			TopologyKey: v1.LabelHostname,
+		},
+	}
+	if template.Spec.Affinity == nil {
+		template.Spec.Affinity = &v1.Affinity{}
+	}
+	if template.Spec.Affinity.PodAntiAffinity == nil {
+		template.Spec.Affinity.PodAntiAffinity = &v1.PodAntiAffinity{}
+	}
+	template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, term)
+}
+
+// isRevisionSpreadTerm detects the anti-affinity terms injected by InjectRevisionSpreadAffinity.
+func isRevisionSpreadTerm(term v1.WeightedPodAffinityTerm) bool {
+	selector := term.PodAffinityTerm.LabelSelector
+	if term.PodAffinityTerm.TopologyKey != v1.LabelHostname || selector == nil {
+		return false
+	}
+	if len(selector.MatchLabels) != 0 || len(selector.MatchExpressions) != 1 {
+		return false
+	}
+	requirement := selector.MatchExpressions[0]
+	return requirement.Key == apps.DefaultDeploymentUniqueLabelKey && requirement.Operator == metav1.LabelSelectorOpIn
+}
+
+// stripRevisionSpreadAffinity removes the anti-affinity terms injected by
+// InjectRevisionSpreadAffinity so template comparisons only reflect user intent.
+func stripRevisionSpreadAffinity(template *v1.PodTemplateSpec) {
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return
+	}
+	var kept []v1.WeightedPodAffinityTerm
+	for _, term := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if !isRevisionSpreadTerm(term) {
+			kept = append(kept, term)
+		}
+	}
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = kept
+	if len(kept) == 0 && len(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) == 0 {
+		affinity.PodAntiAffinity = nil
+	}
+	if affinity.PodAntiAffinity == nil && affinity.PodAffinity == nil && affinity.NodeAffinity == nil {
+		template.Spec.Affinity = nil
+	}
+}
+
 // EqualIgnoreHash returns true if two given podTemplateSpec are equal, ignoring the diff in value of Labels[pod-template-hash]
 // We ignore pod-template-hash because:
 // 1. The hash result would be different upon podTemplateSpec API changes
 //    (e.g. the addition of a new field will cause the hash code to change)
 // 2. The deployment template won't have hash labels
+// Anti-affinity terms injected for revision spreading are controller-owned and are ignored
+// as well.
 func EqualIgnoreHash(template1, template2 *v1.PodTemplateSpec) bool {
 	t1Copy := template1.DeepCopy()
 	t2Copy := template2.DeepCopy()
 	// Remove hash labels from template.Labels before comparing
 	delete(t1Copy.Labels, apps.DefaultDeploymentUniqueLabelKey)
 	delete(t2Copy.Labels, apps.DefaultDeploymentUniqueLabelKey)
-	return apiequality.Semantic.DeepEqual(t1Copy, t2Copy)
+	stripRevisionSpreadAffinity(t1Copy)
+	stripRevisionSpreadAffinity(t2Copy)
+	if apiequality.Semantic.DeepEqual(t1Copy, t2Copy) {
+		return true
+	}
+	// The templates may differ only in server-defaulted fields, e.g. when a manifest is
+	// re-applied without going through the apiserver's defaulting. Compare once more
+	// with defaulting applied to both sides so such no-op changes don't read as a new
+	// revision and trigger a surprise rollout.
+	return apiequality.Semantic.DeepEqual(applyTemplateDefaults(t1Copy), applyTemplateDefaults(t2Copy))
+}
+
+// applyTemplateDefaults returns the template with the v1 API defaulting applied, for
+// defaulting-insensitive comparisons. The input is not modified.
+func applyTemplateDefaults(template *v1.PodTemplateSpec) *v1.PodTemplateSpec {
+	holder := &v1.PodTemplate{Template: *template.DeepCopy()}
+	corev1.SetObjectDefaults_PodTemplate(holder)
+	return &holder.Template
+}
+
+// GetInPlaceMetadataKeys returns the template metadata keys whose changes the deployment
+// wants applied in place instead of through a new revision.
+func GetInPlaceMetadataKeys(deployment *apps.Deployment) []string {
+	annotationValue, ok := deployment.Annotations[InPlaceMetadataKeysAnnotation]
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, key := range strings.Split(annotationValue, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// EqualIgnoreHashAndMetadataKeys is EqualIgnoreHash with the given template label and
+// annotation keys additionally excluded from the comparison.
+func EqualIgnoreHashAndMetadataKeys(template1, template2 *v1.PodTemplateSpec, keys []string) bool {
+	if len(keys) == 0 {
+		return EqualIgnoreHash(template1, template2)
+	}
+	t1Copy := template1.DeepCopy()
+	t2Copy := template2.DeepCopy()
+	for _, key := range keys {
+		delete(t1Copy.Labels, key)
+		delete(t2Copy.Labels, key)
+		delete(t1Copy.Annotations, key)
+		delete(t2Copy.Annotations, key)
+	}
+	return EqualIgnoreHash(t1Copy, t2Copy)
+}
+
+// SyncInPlaceMetadata copies the values of the deployment's in-place metadata keys from
+// the deployment's pod template to the replica set's pod template, returning true if the
+// replica set template changed.
+func SyncInPlaceMetadata(deployment *apps.Deployment, rs *apps.ReplicaSet) bool {
+	changed := false
+	for _, key := range GetInPlaceMetadataKeys(deployment) {
+		if syncMetadataKey(deployment.Spec.Template.Labels, &rs.Spec.Template.Labels, key) {
+			changed = true
+		}
+		if syncMetadataKey(deployment.Spec.Template.Annotations, &rs.Spec.Template.Annotations, key) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func syncMetadataKey(source map[string]string, target *map[string]string, key string) bool {
+	sourceValue, sourceHas := source[key]
+	targetValue, targetHas := (*target)[key]
+	if sourceHas == targetHas && sourceValue == targetValue {
+		return false
+	}
+	if !sourceHas {
+		delete(*target, key)
+		return true
+	}
+	if *target == nil {
+		*target = make(map[string]string)
+	}
+	(*target)[key] = sourceValue
+	return true
 }
 
 // FindNewReplicaSet returns the new RS this given deployment targets (the one with the same pod template).
 func FindNewReplicaSet(deployment *apps.Deployment, rsList []*apps.ReplicaSet) *apps.ReplicaSet {
+	inPlaceKeys := GetInPlaceMetadataKeys(deployment)
 	sort.Sort(controller.ReplicaSetsByCreationTimestamp(rsList))
 	for i := range rsList {
-		if EqualIgnoreHash(&rsList[i].Spec.Template, &deployment.Spec.Template) {
+		if EqualIgnoreHashAndMetadataKeys(&rsList[i].Spec.Template, &deployment.Spec.Template, inPlaceKeys) {
 			// In rare cases, such as after cluster upgrades, Deployment may end up with
 			// having more than one new ReplicaSets that have the same template as its template,
 			// see https://github.com/kubernetes/kubernetes/issues/40415
@@ -901,6 +1638,26 @@ func ResolveFenceposts(maxSurge, maxUnavailable *intstrutil.IntOrString, desired
 	return int32(surge), int32(unavailable), nil
 }
 
+// RolloutBudgetsResolveToZero reports whether the deployment's declared maxSurge and
+// maxUnavailable both resolve to zero for the current replica count (possible with
+// percentage values rounding down on small deployments). ResolveFenceposts silently
+// coerces this to maxUnavailable=1; this helper exists so the controller can tell the
+// user what their declared budgets actually resolve to.
+func RolloutBudgetsResolveToZero(deployment *apps.Deployment) bool {
+	if !IsRollingUpdate(deployment) || *(deployment.Spec.Replicas) == 0 {
+		return false
+	}
+	surge, err := intstrutil.GetScaledValueFromIntOrPercent(intstrutil.ValueOrDefault(deployment.Spec.Strategy.RollingUpdate.MaxSurge, intstrutil.FromInt(0)), int(*(deployment.Spec.Replicas)), true)
+	if err != nil {
+		return false
+	}
+	unavailable, err := intstrutil.GetScaledValueFromIntOrPercent(intstrutil.ValueOrDefault(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable, intstrutil.FromInt(0)), int(*(deployment.Spec.Replicas)), false)
+	if err != nil {
+		return false
+	}
+	return surge == 0 && unavailable == 0
+}
+
 // HasProgressDeadline checks if the Deployment d is expected to surface the reason
 // "ProgressDeadlineExceeded" when the Deployment progress takes longer than expected time.
 func HasProgressDeadline(d *apps.Deployment) bool {
@@ -950,6 +1707,206 @@ func GetDeploymentsForReplicaSet(deploymentLister appslisters.DeploymentLister,
 	return deployments, nil
 }
 
+// InFreezeWindow reports whether the given time falls inside one of the deployment's
+// declared freeze windows, and if so when the active window ends. See
+// FreezeWindowsAnnotation for the window format.
+func InFreezeWindow(deployment *apps.Deployment, now time.Time) (bool, time.Time) {
+	annotationValue, ok := deployment.Annotations[FreezeWindowsAnnotation]
+	if !ok {
+		return false, time.Time{}
+	}
+	now = now.UTC()
+	for _, window := range strings.Split(annotationValue, ",") {
+		parts := strings.Split(strings.TrimSpace(window), "-")
+		if len(parts) != 2 {
+			klog.V(2).Infof("Ignoring invalid freeze window %q of deployment %q", window, deployment.Name)
+			continue
+		}
+		startClock, startErr := time.Parse("15:04", parts[0])
+		endClock, endErr := time.Parse("15:04", parts[1])
+		if startErr != nil || endErr != nil {
+			klog.V(2).Infof("Ignoring invalid freeze window %q of deployment %q", window, deployment.Name)
+			continue
+		}
+		start := time.Date(now.Year(), now.Month(), now.Day(), startClock.Hour(), startClock.Minute(), 0, 0, time.UTC)
+		end := time.Date(now.Year(), now.Month(), now.Day(), endClock.Hour(), endClock.Minute(), 0, 0, time.UTC)
+		if !end.After(start) {
+			// The window wraps around midnight.
+			if now.Before(end) {
+				start = start.AddDate(0, 0, -1)
+			} else {
+				end = end.AddDate(0, 0, 1)
+			}
+		}
+		if !now.Before(start) && now.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// SurgePodFailureActionPause pauses the deployment when surge pod recreation retries are
+// exhausted. Any other value of the failure action annotation only produces an event.
+const SurgePodFailureActionPause = "Pause"
+
+// GetSurgePodTTL returns the maximum time a new-revision pod may stay unready before the
+// controller recreates it, as requested by the deployment's annotations.
+func GetSurgePodTTL(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, SurgePodTTLAnnotation)
+}
+
+// GetAutoPromoteAfter returns the bake duration after which a paused, healthy deployment
+// is automatically resumed, as requested by the deployment's annotations.
+func GetAutoPromoteAfter(deployment *apps.Deployment) (time.Duration, bool) {
+	return getDurationFromAnnotation(deployment, AutoPromoteAfterAnnotation)
+}
+
+// CrashLoopActionPause pauses the deployment instead of rolling back when the crash-loop
+// threshold is reached.
+const CrashLoopActionPause = "Pause"
+
+// defaultCrashLoopWindow bounds the crash-loop check when no window is configured.
+const defaultCrashLoopWindow = 10 * time.Minute
+
+// GetCrashLoopThreshold returns the number of crash-looping new-revision pods at which
+// the controller reacts, as requested by the deployment's annotations.
+func GetCrashLoopThreshold(deployment *apps.Deployment) (int32, bool) {
+	annotationValue, ok := deployment.Annotations[CrashLoopThresholdAnnotation]
+	if !ok {
+		return 0, false
+	}
+	intValue, err := strconv.Atoi(annotationValue)
+	if err != nil || intValue <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a threshold", annotationValue, CrashLoopThresholdAnnotation, deployment.Name)
+		return 0, false
+	}
+	return int32(intValue), true
+}
+
+// GetCrashLoopWindow returns how long after the new replica set's creation the crash-loop
+// threshold applies.
+func GetCrashLoopWindow(deployment *apps.Deployment) time.Duration {
+	if window, ok := getDurationFromAnnotation(deployment, CrashLoopWindowAnnotation); ok {
+		return window
+	}
+	return defaultCrashLoopWindow
+}
+
+func getDurationFromAnnotation(deployment *apps.Deployment, annotationKey string) (time.Duration, bool) {
+	annotationValue, ok := deployment.Annotations[annotationKey]
+	if !ok {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(annotationValue)
+	if err != nil || duration <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a duration", annotationValue, annotationKey, deployment.Name)
+		return 0, false
+	}
+	return duration, true
+}
+
+// GetSurgePodRestarts returns how many unready surge pods of the given replica set the
+// controller has already deleted.
+func GetSurgePodRestarts(rs *apps.ReplicaSet) int32 {
+	restarts, _ := getIntFromAnnotation(rs, SurgePodRestartsAnnotation)
+	return restarts
+}
+
+// GetDualRevisionRatio returns the steady-state replica split between the new and the
+// latest old revision requested by the deployment's annotations, if any.
+func GetDualRevisionRatio(deployment *apps.Deployment) (int32, int32, bool) {
+	annotationValue, ok := deployment.Annotations[DualRevisionRatioAnnotation]
+	if !ok {
+		return 0, 0, false
+	}
+	parts := strings.Split(annotationValue, ":")
+	if len(parts) != 2 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a revision ratio", annotationValue, DualRevisionRatioAnnotation, deployment.Name)
+		return 0, 0, false
+	}
+	newPortion, newErr := strconv.Atoi(parts[0])
+	oldPortion, oldErr := strconv.Atoi(parts[1])
+	if newErr != nil || oldErr != nil || newPortion <= 0 || oldPortion <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a revision ratio", annotationValue, DualRevisionRatioAnnotation, deployment.Name)
+		return 0, 0, false
+	}
+	return int32(newPortion), int32(oldPortion), true
+}
+
+// GetRollingBatchSize returns the fixed batch size requested by the deployment's
+// annotations, if any. Batches only apply to rolling updates.
+func GetRollingBatchSize(deployment *apps.Deployment) (int32, bool) {
+	if !IsRollingUpdate(deployment) {
+		return 0, false
+	}
+	annotationValue, ok := deployment.Annotations[RollingBatchSizeAnnotation]
+	if !ok {
+		return 0, false
+	}
+	intValue, err := strconv.Atoi(annotationValue)
+	if err != nil || intValue <= 0 {
+		klog.V(2).Infof("Cannot use the value %q of annotation %q for deployment %q as a batch size", annotationValue, RollingBatchSizeAnnotation, deployment.Name)
+		return 0, false
+	}
+	return int32(intValue), true
+}
+
+// BatchLimitedReplicas caps the desired size of the new replica set so that a batched
+// rolling update grows it by at most batchSize replicas beyond the ones that are already
+// available. While a previous batch has unavailable replicas the current size is kept, so
+// the next batch only starts once the previous one is fully available.
+func BatchLimitedReplicas(newRS *apps.ReplicaSet, desiredReplicas, batchSize int32) int32 {
+	currentReplicas := *(newRS.Spec.Replicas)
+	if currentReplicas > newRS.Status.AvailableReplicas {
+		// The previous batch has not become available yet.
+		return currentReplicas
+	}
+	nextBatch := newRS.Status.AvailableReplicas + batchSize
+	if nextBatch > desiredReplicas {
+		nextBatch = desiredReplicas
+	}
+	if nextBatch < currentReplicas {
+		nextBatch = currentReplicas
+	}
+	return nextBatch
+}
+
+// ScaleDownOrder is the policy controlling the order in which old replica sets are scaled
+// down during a rolling update.
+type ScaleDownOrder string
+
+const (
+	// ScaleDownOldestFirst scales down the oldest replica sets first. This is the default.
+	ScaleDownOldestFirst ScaleDownOrder = "OldestFirst"
+	// ScaleDownNewestFirst scales down the newest old replica sets first.
+	ScaleDownNewestFirst ScaleDownOrder = "NewestFirst"
+	// ScaleDownLeastReadyFirst scales down the replica sets with the fewest ready replicas first.
+	ScaleDownLeastReadyFirst ScaleDownOrder = "LeastReadyFirst"
+)
+
+// GetScaleDownOrder returns the scale-down ordering policy requested by the deployment's
+// annotations, defaulting to ScaleDownOldestFirst for missing or unknown values.
+func GetScaleDownOrder(deployment *apps.Deployment) ScaleDownOrder {
+	switch order := ScaleDownOrder(deployment.Annotations[ScaleDownOrderAnnotation]); order {
+	case ScaleDownNewestFirst, ScaleDownLeastReadyFirst:
+		return order
+	}
+	return ScaleDownOldestFirst
+}
+
+// ReplicaSetsByReadyReplicas sorts a list of ReplicaSet by ready replicas in ascending order,
+// using their creation timestamp as a tie breaker.
+type ReplicaSetsByReadyReplicas []*apps.ReplicaSet
+
+func (o ReplicaSetsByReadyReplicas) Len() int      { return len(o) }
+func (o ReplicaSetsByReadyReplicas) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+func (o ReplicaSetsByReadyReplicas) Less(i, j int) bool {
+	if o[i].Status.ReadyReplicas == o[j].Status.ReadyReplicas {
+		return controller.ReplicaSetsByCreationTimestamp(o).Less(i, j)
+	}
+	return o[i].Status.ReadyReplicas < o[j].Status.ReadyReplicas
+}
+
 // ReplicaSetsByRevision sorts a list of ReplicaSet by revision, using their creation timestamp or name as a tie breaker.
 // By using the creation timestamp, this sorts from old to new replica sets.
 type ReplicaSetsByRevision []*apps.ReplicaSet
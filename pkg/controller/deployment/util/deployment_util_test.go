@@ -1484,3 +1484,481 @@ func TestGetDeploymentsForReplicaSet(t *testing.T) {
 	}
 
 }
+
+func TestGetScaleDownOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		expected   ScaleDownOrder
+	}{
+		{
+			name:     "no annotation defaults to oldest first",
+			expected: ScaleDownOldestFirst,
+		},
+		{
+			name:       "newest first",
+			annotation: string(ScaleDownNewestFirst),
+			expected:   ScaleDownNewestFirst,
+		},
+		{
+			name:       "least ready first",
+			annotation: string(ScaleDownLeastReadyFirst),
+			expected:   ScaleDownLeastReadyFirst,
+		},
+		{
+			name:       "unknown value falls back to oldest first",
+			annotation: "Random",
+			expected:   ScaleDownOldestFirst,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := generateDeployment("busybox")
+			if test.annotation != "" {
+				d.Annotations = map[string]string{ScaleDownOrderAnnotation: test.annotation}
+			}
+			if order := GetScaleDownOrder(&d); order != test.expected {
+				t.Errorf("expected scale-down order %q, got %q", test.expected, order)
+			}
+		})
+	}
+}
+
+func TestBatchLimitedReplicas(t *testing.T) {
+	tests := []struct {
+		name              string
+		specReplicas      int32
+		availableReplicas int32
+		desiredReplicas   int32
+		batchSize         int32
+		expected          int32
+	}{
+		{
+			name:              "first batch",
+			specReplicas:      0,
+			availableReplicas: 0,
+			desiredReplicas:   10,
+			batchSize:         3,
+			expected:          3,
+		},
+		{
+			name:              "previous batch not yet available",
+			specReplicas:      3,
+			availableReplicas: 1,
+			desiredReplicas:   10,
+			batchSize:         3,
+			expected:          3,
+		},
+		{
+			name:              "previous batch available starts the next one",
+			specReplicas:      3,
+			availableReplicas: 3,
+			desiredReplicas:   10,
+			batchSize:         3,
+			expected:          6,
+		},
+		{
+			name:              "last batch is capped at the desired size",
+			specReplicas:      9,
+			availableReplicas: 9,
+			desiredReplicas:   10,
+			batchSize:         3,
+			expected:          10,
+		},
+		{
+			name:              "never shrinks below the current size",
+			specReplicas:      5,
+			availableReplicas: 5,
+			desiredReplicas:   10,
+			batchSize:         0,
+			expected:          5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rs := generateRS(generateDeployment("busybox"))
+			*(rs.Spec.Replicas) = test.specReplicas
+			rs.Status.AvailableReplicas = test.availableReplicas
+			if got := BatchLimitedReplicas(&rs, test.desiredReplicas, test.batchSize); got != test.expected {
+				t.Errorf("expected %d replicas, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetRollingBatchSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotation    string
+		strategyType  apps.DeploymentStrategyType
+		expectedSize  int32
+		expectedFound bool
+	}{
+		{
+			name:         "no annotation",
+			strategyType: apps.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:          "valid batch size",
+			annotation:    "5",
+			strategyType:  apps.RollingUpdateDeploymentStrategyType,
+			expectedSize:  5,
+			expectedFound: true,
+		},
+		{
+			name:         "non-positive batch size is ignored",
+			annotation:   "0",
+			strategyType: apps.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:         "invalid batch size is ignored",
+			annotation:   "all",
+			strategyType: apps.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:         "recreate deployments never batch",
+			annotation:   "5",
+			strategyType: apps.RecreateDeploymentStrategyType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := generateDeployment("busybox")
+			d.Spec.Strategy.Type = test.strategyType
+			if test.annotation != "" {
+				d.Annotations = map[string]string{RollingBatchSizeAnnotation: test.annotation}
+			}
+			size, found := GetRollingBatchSize(&d)
+			if found != test.expectedFound || size != test.expectedSize {
+				t.Errorf("expected (%d, %t), got (%d, %t)", test.expectedSize, test.expectedFound, size, found)
+			}
+		})
+	}
+}
+
+func TestInFreezeWindow(t *testing.T) {
+	now := time.Date(2021, time.April, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		windows        string
+		expectedFrozen bool
+		expectedEnd    time.Time
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:           "inside a window",
+			windows:        "11:00-13:00",
+			expectedFrozen: true,
+			expectedEnd:    time.Date(2021, time.April, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "outside all windows",
+			windows: "02:00-04:00,22:00-23:00",
+		},
+		{
+			name:           "window wrapping midnight",
+			windows:        "23:00-12:30",
+			expectedFrozen: true,
+			expectedEnd:    time.Date(2021, time.April, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid entries are ignored",
+			windows: "not-a-window,25:99-13:00",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := generateDeployment("busybox")
+			if test.windows != "" {
+				d.Annotations = map[string]string{FreezeWindowsAnnotation: test.windows}
+			}
+			frozen, end := InFreezeWindow(&d, now)
+			if frozen != test.expectedFrozen {
+				t.Errorf("expected frozen=%t, got %t", test.expectedFrozen, frozen)
+			}
+			if test.expectedFrozen && !end.Equal(test.expectedEnd) {
+				t.Errorf("expected window end %v, got %v", test.expectedEnd, end)
+			}
+		})
+	}
+}
+
+func TestRevisionSpreadAffinity(t *testing.T) {
+	d := generateDeployment("busybox")
+	template := d.Spec.Template.DeepCopy()
+
+	InjectRevisionSpreadAffinity(template, []string{"hash-2", "hash-1"})
+	antiAffinity := template.Spec.Affinity.PodAntiAffinity
+	if len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Fatalf("expected 1 injected anti-affinity term, got %d", len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution))
+	}
+	term := antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0]
+	if term.PodAffinityTerm.TopologyKey != v1.LabelHostname {
+		t.Errorf("expected topology key %q, got %q", v1.LabelHostname, term.PodAffinityTerm.TopologyKey)
+	}
+	expectedValues := []string{"hash-1", "hash-2"}
+	if !reflect.DeepEqual(term.PodAffinityTerm.LabelSelector.MatchExpressions[0].Values, expectedValues) {
+		t.Errorf("expected old hashes %v, got %v", expectedValues, term.PodAffinityTerm.LabelSelector.MatchExpressions[0].Values)
+	}
+
+	// Injected terms must not make the template look different from the user's template.
+	if !EqualIgnoreHash(template, &d.Spec.Template) {
+		t.Errorf("expected templates to be equal ignoring the injected spread affinity")
+	}
+
+	// Injecting nothing is a no-op.
+	noop := d.Spec.Template.DeepCopy()
+	InjectRevisionSpreadAffinity(noop, nil)
+	if noop.Spec.Affinity != nil {
+		t.Errorf("expected no affinity to be injected for empty hash list")
+	}
+}
+
+func TestGetDualRevisionRatio(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotation    string
+		expectedNew   int32
+		expectedOld   int32
+		expectedFound bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:          "valid ratio",
+			annotation:    "90:10",
+			expectedNew:   90,
+			expectedOld:   10,
+			expectedFound: true,
+		},
+		{
+			name:       "zero portion is ignored",
+			annotation: "100:0",
+		},
+		{
+			name:       "invalid format is ignored",
+			annotation: "90/10",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := generateDeployment("busybox")
+			if test.annotation != "" {
+				d.Annotations = map[string]string{DualRevisionRatioAnnotation: test.annotation}
+			}
+			newPortion, oldPortion, found := GetDualRevisionRatio(&d)
+			if found != test.expectedFound || newPortion != test.expectedNew || oldPortion != test.expectedOld {
+				t.Errorf("expected (%d, %d, %t), got (%d, %d, %t)", test.expectedNew, test.expectedOld, test.expectedFound, newPortion, oldPortion, found)
+			}
+		})
+	}
+}
+
+func TestEqualIgnoreHashAndMetadataKeys(t *testing.T) {
+	d := generateDeployment("busybox")
+	modified := d.Spec.Template.DeepCopy()
+	if modified.Annotations == nil {
+		modified.Annotations = make(map[string]string)
+	}
+	modified.Annotations["team"] = "platform"
+	modified.Labels["observability"] = "enabled"
+
+	if EqualIgnoreHash(modified, &d.Spec.Template) {
+		t.Fatalf("expected templates with extra metadata to differ without in-place keys")
+	}
+	if !EqualIgnoreHashAndMetadataKeys(modified, &d.Spec.Template, []string{"team", "observability"}) {
+		t.Errorf("expected templates to be equal ignoring the in-place keys")
+	}
+	if EqualIgnoreHashAndMetadataKeys(modified, &d.Spec.Template, []string{"team"}) {
+		t.Errorf("expected templates to differ when only some changed keys are ignored")
+	}
+}
+
+func TestSyncInPlaceMetadata(t *testing.T) {
+	d := generateDeployment("busybox")
+	d.Annotations = map[string]string{InPlaceMetadataKeysAnnotation: "team, observability"}
+	d.Spec.Template.Annotations = map[string]string{"team": "platform"}
+	d.Spec.Template.Labels["observability"] = "enabled"
+
+	rs := generateRS(d)
+	rs.Spec.Template.Annotations = map[string]string{"team": "legacy"}
+
+	if changed := SyncInPlaceMetadata(&d, &rs); !changed {
+		t.Fatalf("expected the replica set template to be updated")
+	}
+	if rs.Spec.Template.Annotations["team"] != "platform" {
+		t.Errorf("expected annotation to be synced, got %q", rs.Spec.Template.Annotations["team"])
+	}
+	if rs.Spec.Template.Labels["observability"] != "enabled" {
+		t.Errorf("expected label to be synced, got %q", rs.Spec.Template.Labels["observability"])
+	}
+	if changed := SyncInPlaceMetadata(&d, &rs); changed {
+		t.Errorf("expected a second sync to be a no-op")
+	}
+}
+
+func TestTemplateDiffSummary(t *testing.T) {
+	base := generateDeployment("nginx:1.0").Spec.Template
+
+	updated := base.DeepCopy()
+	updated.Spec.Containers[0].Image = "nginx:2.0"
+	if got := TemplateDiffSummary(&base, updated); got != `container "nginx:1.0" image nginx:1.0 -> nginx:2.0` {
+		t.Errorf("unexpected diff summary: %q", got)
+	}
+
+	added := base.DeepCopy()
+	added.Spec.Containers = append(added.Spec.Containers, v1.Container{Name: "sidecar", Image: "envoy"})
+	if got := TemplateDiffSummary(&base, added); got != `container "sidecar" added` {
+		t.Errorf("unexpected diff summary: %q", got)
+	}
+
+	relabeled := base.DeepCopy()
+	relabeled.Labels = map[string]string{"new": "label"}
+	if got := TemplateDiffSummary(&base, relabeled); got != "other pod template fields differ" {
+		t.Errorf("unexpected diff summary: %q", got)
+	}
+
+	if got := TemplateDiffSummary(&base, base.DeepCopy()); got != "" {
+		t.Errorf("expected empty diff summary for identical templates, got %q", got)
+	}
+}
+
+func TestFindRollbackTarget(t *testing.T) {
+	makeRS := func(name string, revision string, blocked bool) *apps.ReplicaSet {
+		rs := generateRSWithLabel(map[string]string{"foo": "bar"}, "busybox")
+		rs.Name = name
+		rs.Annotations = map[string]string{RevisionAnnotation: revision}
+		if blocked {
+			rs.Annotations[RevisionBlockedAnnotation] = "true"
+		}
+		return &rs
+	}
+	rs1 := makeRS("rs1", "1", false)
+	rs2 := makeRS("rs2", "2", true)
+	rs3 := makeRS("rs3", "3", false)
+	allRSs := []*apps.ReplicaSet{rs1, rs2, rs3}
+
+	if target, blocked := FindRollbackTarget(allRSs, 3, false); target != rs3 || blocked {
+		t.Errorf("expected unblocked revision 3 to be returned, got %v (blocked=%t)", target, blocked)
+	}
+	if target, blocked := FindRollbackTarget(allRSs, 2, false); target != nil || !blocked {
+		t.Errorf("expected explicit rollback to blocked revision 2 to be rejected, got %v (blocked=%t)", target, blocked)
+	}
+	if target, blocked := FindRollbackTarget(allRSs, 2, true); target != rs1 || !blocked {
+		t.Errorf("expected fallback to revision 1, got %v (blocked=%t)", target, blocked)
+	}
+	if target, blocked := FindRollbackTarget(allRSs, 9, true); target != nil || blocked {
+		t.Errorf("expected missing revision to return nothing, got %v (blocked=%t)", target, blocked)
+	}
+}
+
+func TestCompanionAnnotationSnapshotAndRestore(t *testing.T) {
+	d := generateDeployment("busybox")
+	d.Annotations = map[string]string{
+		RevisionCompanionKeysAnnotation: "hpa.example.com/hint, kubernetes.io/change-cause",
+		"hpa.example.com/hint":          "v1",
+		"kubernetes.io/change-cause":    "create",
+	}
+	rs := generateRS(d)
+
+	if changed := SnapshotCompanionAnnotations(&d, &rs); !changed {
+		t.Fatalf("expected companion values to be snapshotted")
+	}
+	if rs.Annotations["snapshot.deployment.kubernetes.io/hpa.example.com/hint"] != "v1" {
+		t.Errorf("expected snapshot of companion value, got %v", rs.Annotations)
+	}
+	if changed := SnapshotCompanionAnnotations(&d, &rs); changed {
+		t.Errorf("expected a second snapshot to be a no-op")
+	}
+
+	// Snapshots must not be copied around as regular annotations.
+	if !skipCopyAnnotation("snapshot.deployment.kubernetes.io/hpa.example.com/hint") {
+		t.Errorf("expected snapshot annotations to be skipped by the annotation copy")
+	}
+
+	// After the deployment moved on, a rollback restores the snapshotted values.
+	d.Annotations["hpa.example.com/hint"] = "v2"
+	delete(d.Annotations, "kubernetes.io/change-cause")
+	RestoreCompanionAnnotations(&d, &rs)
+	if d.Annotations["hpa.example.com/hint"] != "v1" {
+		t.Errorf("expected companion value to be restored, got %q", d.Annotations["hpa.example.com/hint"])
+	}
+	if d.Annotations["kubernetes.io/change-cause"] != "create" {
+		t.Errorf("expected removed companion value to be restored, got %q", d.Annotations["kubernetes.io/change-cause"])
+	}
+}
+
+func TestAnnotationPropagationPolicy(t *testing.T) {
+	defer SetAnnotationPropagationPolicy(nil, nil)
+
+	d := generateDeployment("busybox")
+	d.Annotations = map[string]string{
+		"team.example.com/owner": "platform",
+		"internal.example.com/x": "noise",
+		"kubernetes.io/whatever": "keep",
+	}
+
+	SetAnnotationPropagationPolicy(nil, []string{"internal.example.com/*"})
+	rs := generateRS(d)
+	rs.Annotations = nil
+	copyDeploymentAnnotationsToReplicaSet(&d, &rs)
+	if _, ok := rs.Annotations["internal.example.com/x"]; ok {
+		t.Errorf("expected excluded annotation not to propagate")
+	}
+	if rs.Annotations["team.example.com/owner"] != "platform" {
+		t.Errorf("expected non-excluded annotation to propagate")
+	}
+
+	SetAnnotationPropagationPolicy([]string{"team.example.com/*"}, nil)
+	rs = generateRS(d)
+	rs.Annotations = nil
+	copyDeploymentAnnotationsToReplicaSet(&d, &rs)
+	if rs.Annotations["team.example.com/owner"] != "platform" {
+		t.Errorf("expected included annotation to propagate")
+	}
+	if _, ok := rs.Annotations["kubernetes.io/whatever"]; ok {
+		t.Errorf("expected annotation outside the include list not to propagate")
+	}
+}
+
+func TestEqualIgnoreHashIgnoresDefaulting(t *testing.T) {
+	base := generateDeployment("busybox").Spec.Template
+
+	defaulted := base.DeepCopy()
+	defaulted.Spec.RestartPolicy = v1.RestartPolicyAlways
+	defaulted.Spec.SchedulerName = v1.DefaultSchedulerName
+	defaulted.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+
+	if !EqualIgnoreHash(&base, defaulted) {
+		t.Errorf("expected templates differing only in defaulted fields to be equal")
+	}
+
+	changed := base.DeepCopy()
+	changed.Spec.RestartPolicy = v1.RestartPolicyNever
+	if EqualIgnoreHash(&base, changed) {
+		t.Errorf("expected templates with a non-default value to differ")
+	}
+}
+
+func TestNewRSName(t *testing.T) {
+	d := generateDeployment("busybox")
+	if got := NewRSName(&d, "4", "abcd"); got != "busybox-abcd" {
+		t.Errorf("unexpected default replica set name %q", got)
+	}
+	d.Annotations = map[string]string{RSNamingSchemeAnnotation: RSNamingSchemeRevisionHash}
+	if got := NewRSName(&d, "4", "abcd"); got != "busybox-4-abcd" {
+		t.Errorf("unexpected revision-hash replica set name %q", got)
+	}
+	d.Annotations[RSNamingSchemeAnnotation] = "bogus"
+	if got := NewRSName(&d, "4", "abcd"); got != "busybox-abcd" {
+		t.Errorf("expected unknown schemes to fall back to the default name, got %q", got)
+	}
+}
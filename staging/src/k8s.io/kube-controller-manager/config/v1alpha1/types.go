@@ -224,6 +224,54 @@ type DeploymentControllerConfiguration struct {
 	ConcurrentDeploymentSyncs int32
 	// deploymentControllerSyncPeriod is the period for syncing the deployments.
 	DeploymentControllerSyncPeriod metav1.Duration
+	// mutationAuditLogPath, when set, enables the controller's JSONL audit log of
+	// mutating API calls and names the file entries are appended to.
+	MutationAuditLogPath string
+	// nodeUnavailabilityThresholdPercent, when positive, holds rollout progression while
+	// more than this percentage of nodes is unschedulable or not ready.
+	NodeUnavailabilityThresholdPercent int32
+	// rollbackWebhookURL, when set, receives a JSON POST for every automatic rollback
+	// the controller performs.
+	RollbackWebhookURL string
+	// annotationPropagationInclude restricts which deployment annotations are copied to
+	// replica sets (path.Match globs). Empty means all non-skipped annotations.
+	AnnotationPropagationInclude []string
+	// annotationPropagationExclude lists deployment annotations never copied to replica
+	// sets (path.Match globs); exclusions win over inclusions.
+	AnnotationPropagationExclude []string
+	// disableOrphanAdoption, when true, keeps the controller from adopting orphaned
+	// replica sets.
+	DisableOrphanAdoption bool
+	// progressRecheckMinInterval, when positive, is the soonest a potentially stuck
+	// deployment is requeued for a progress check.
+	ProgressRecheckMinInterval metav1.Duration
+	// progressRecheckMaxInterval, when positive, is the latest a potentially stuck
+	// deployment is requeued for a progress check.
+	ProgressRecheckMaxInterval metav1.Duration
+	// serverSideApplyStatus, when true, writes deployment status with server-side apply
+	// under a dedicated field manager instead of UpdateStatus.
+	ServerSideApplyStatus bool
+	// statusWriteMinInterval, when positive, bounds how often status is written per
+	// deployment; faster writes are coalesced into a later sync.
+	StatusWriteMinInterval metav1.Duration
+	// shardIndex/shardCount restrict this controller instance to a consistent-hash
+	// shard of the namespaces; shardCount of at most 1 disables sharding.
+	ShardIndex int32
+	ShardCount int32
+	// watchLabelSelector, when set, restricts the deployment, replica set and pod
+	// informers of this controller to objects matching the label selector, cutting
+	// cache memory for dedicated tenant controllers.
+	WatchLabelSelector string
+	// queueBaseDelay/queueMaxDelay, when both positive, tune the exponential per-item
+	// backoff of the sync queues.
+	QueueBaseDelay metav1.Duration
+	QueueMaxDelay  metav1.Duration
+	// queueMaxRetries, when positive, caps how often a failing sync is retried before
+	// the deployment is dropped from the queue.
+	QueueMaxRetries int32
+	// maxConcurrentDeploymentSyncs, when larger than concurrentDeploymentSyncs, lets
+	// the controller grow its worker pool up to this size while the queue stays deep.
+	MaxConcurrentDeploymentSyncs int32
 }
 
 // StatefulSetControllerConfiguration contains elements describing StatefulSetController.
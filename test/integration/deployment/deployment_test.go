@@ -57,7 +57,7 @@ func TestNewDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
@@ -124,7 +124,7 @@ func TestDeploymentRollingUpdate(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	replicas := int32(20)
 	tester := &deploymentTester{t: t, c: c, deployment: newDeployment(name, ns.Name, replicas)}
@@ -267,7 +267,7 @@ func TestPausedDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Verify that the paused deployment won't create new replica set.
 	if err := tester.expectNoNewReplicaSet(); err != nil {
@@ -368,7 +368,7 @@ func TestScalePausedDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
@@ -449,7 +449,7 @@ func TestDeploymentHashCollision(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
@@ -552,7 +552,7 @@ func TestFailedDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	if err = tester.waitForDeploymentUpdatedReplicasGTE(replicas); err != nil {
 		t.Fatal(err)
@@ -594,7 +594,7 @@ func TestOverlappingDeployments(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Create 2 deployments with overlapping selectors
 	var err error
@@ -668,7 +668,7 @@ func TestScaledRolloutDeployment(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Create a deployment with rolling update strategy, max surge = 3, and max unavailable = 2
 	var err error
@@ -871,7 +871,7 @@ func TestSpecReplicasChange(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Scale up/down deployment and verify its replicaset has matching .spec.replicas
 	if err = tester.scaleDeployment(2); err != nil {
@@ -929,7 +929,7 @@ func TestDeploymentAvailableCondition(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the deployment to be observed by the controller and has at least specified number of updated replicas
 	if err = tester.waitForDeploymentUpdatedReplicasGTE(replicas); err != nil {
@@ -1046,7 +1046,7 @@ func TestGeneralReplicaSetAdoption(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
@@ -1138,7 +1138,7 @@ func TestDeploymentScaleSubresource(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
@@ -1182,7 +1182,7 @@ func TestReplicaSetOrphaningAndAdoptionWhenLabelsChange(t *testing.T) {
 	defer close(stopCh)
 	informers.Start(stopCh)
 	go rm.Run(5, stopCh)
-	go dc.Run(5, stopCh)
+	go runControllerWithStop(dc, 5, stopCh)
 
 	// Wait for the Deployment to be updated to revision 1
 	if err := tester.waitForDeploymentRevisionAndImage("1", fakeImage); err != nil {
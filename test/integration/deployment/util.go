@@ -118,6 +118,8 @@ func dcSetup(t *testing.T) (*httptest.Server, framework.CloseFunc, *replicaset.R
 		informers.Apps().V1().Deployments(),
 		informers.Apps().V1().ReplicaSets(),
 		informers.Core().V1().Pods(),
+		informers.Policy().V1().PodDisruptionBudgets(),
+		informers.Core().V1().Nodes(),
 		clientset.NewForConfigOrDie(restclient.AddUserAgent(&config, "deployment-controller")),
 	)
 	if err != nil {
@@ -450,3 +452,13 @@ func (d *deploymentTester) checkDeploymentStatusReplicasFields(replicas, updated
 	}
 	return nil
 }
+
+// runControllerWithStop adapts a stop channel to the controller's context-aware Run.
+func runControllerWithStop(dc *deployment.DeploymentController, workers int, stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	dc.Run(ctx, workers)
+}